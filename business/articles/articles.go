@@ -2,11 +2,34 @@ package articles
 
 import (
 	"errors"
+	"strings"
 	"time"
 )
 
 var ErrArticleNotFound = errors.New("article not found")
 
+// Status is the publishing state of an article
+type Status string
+
+const (
+	// StatusDraft articles are never published
+	StatusDraft Status = "draft"
+	// StatusScheduled articles are published once their publish time passes
+	StatusScheduled Status = "scheduled"
+	// StatusPublished articles are published, articles without a status are considered published
+	StatusPublished Status = "published"
+)
+
+// ParseStatus returns the Status named s case insensitively, unknown names are drafts so they are not published by mistake
+func ParseStatus(s string) Status {
+	switch status := Status(strings.ToLower(strings.TrimSpace(s))); status {
+	case "", StatusScheduled, StatusPublished:
+		return status
+	default:
+		return StatusDraft
+	}
+}
+
 // Article is a blog article
 type Article struct {
 	ID             string    `json:"id"`
@@ -15,4 +38,30 @@ type Article struct {
 	Excerpt        string    `json:"excerpt"`
 	WrittenAt      time.Time `json:"written_at"`
 	Slug           string    `json:"slug"`
+	Tags           []string  `json:"tags"`
+	Status         Status    `json:"status"`
+	// PublishAt is when a scheduled article is published, WrittenAt is used when it's not set
+	PublishAt time.Time `json:"publish_at"`
+}
+
+// PublishTime is the time the article is published at, zero if it is not set
+func (a Article) PublishTime() time.Time {
+	if !a.PublishAt.IsZero() {
+		return a.PublishAt
+	}
+	return a.WrittenAt
+}
+
+// IsPublished reports whether the article is visible at now, drafts never are and
+// scheduled or published articles are once their publish time passes
+func (a Article) IsPublished(now time.Time) bool {
+	switch a.Status {
+	case StatusDraft:
+		return false
+	case StatusScheduled:
+		// a schedule without time is never due
+		return !a.PublishTime().IsZero() && !now.Before(a.PublishTime())
+	default:
+		return !now.Before(a.PublishTime())
+	}
 }
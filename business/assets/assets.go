@@ -6,6 +6,7 @@ import (
 	"io"
 	"io/fs"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 )
@@ -21,10 +22,21 @@ func New() *Assets {
 	return &Assets{files}
 }
 
+// ServeHTTP serves embedded files under their URLs and fingerprinted URLs
 func (a *Assets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if original, ok := manifest.original[r.URL.Path]; ok {
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = original
+		r2.URL.RawPath = ""
+		r = r2
+	}
 	http.FileServer(http.FS(a)).ServeHTTP(w, r)
 }
 
+// RecursiveCopy copies embedded files to target under both their names and fingerprinted names
 func (a *Assets) RecursiveCopy(target string, perm os.FileMode) error {
 	err := fs.WalkDir(a, ".", func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -37,20 +49,14 @@ func (a *Assets) RecursiveCopy(target string, perm os.FileMode) error {
 			return os.MkdirAll(targetPath, perm)
 		}
 
-		src, err := files.Open(path)
-		if err != nil {
-			return fmt.Errorf("open src %s: %w", path, err)
-		}
-		defer src.Close()
-
-		dst, err := os.Create(targetPath)
-		if err != nil {
-			return fmt.Errorf("create dst: %w", err)
+		if err := copyFile(path, targetPath); err != nil {
+			return err
 		}
-		defer dst.Close()
 
-		if _, err := io.Copy(dst, src); err != nil {
-			return fmt.Errorf("copy src to dst: %w", err)
+		if fingerprinted, ok := manifest.fingerprinted["/"+path]; ok {
+			if err := copyFile(path, filepath.Join(target, fingerprinted)); err != nil {
+				return fmt.Errorf("copy fingerprinted: %w", err)
+			}
 		}
 
 		return nil
@@ -62,3 +68,24 @@ func (a *Assets) RecursiveCopy(target string, perm os.FileMode) error {
 
 	return nil
 }
+
+// copyFile copies the embedded file at path to targetPath
+func copyFile(path string, targetPath string) error {
+	src, err := files.Open(path)
+	if err != nil {
+		return fmt.Errorf("open src %s: %w", path, err)
+	}
+	defer src.Close()
+
+	dst, err := os.Create(targetPath)
+	if err != nil {
+		return fmt.Errorf("create dst: %w", err)
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return fmt.Errorf("copy src to dst: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package assets
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFingerprint(t *testing.T) {
+	content := []byte("body { color: white; }")
+
+	url := fingerprint("/static/css/tailwind.css", content)
+	if !regexp.MustCompile(`^/static/css/tailwind\.[0-9a-f]{12}\.css$`).MatchString(url) {
+		t.Errorf("hash should be inserted before the extension, got: %s", url)
+	}
+	if fingerprint("/static/css/tailwind.css", content) != url {
+		t.Error("same content should have the same fingerprint")
+	}
+	if fingerprint("/static/css/tailwind.css", []byte("body { color: black; }")) == url {
+		t.Error("changed content should change the fingerprint")
+	}
+	if got := fingerprint("/static/LICENSE", content); !regexp.MustCompile(`^/static/LICENSE\.[0-9a-f]{12}$`).MatchString(got) {
+		t.Errorf("hash should be appended to names without extension, got: %s", got)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	fsys := fstest.MapFS{
+		"static/css/tailwind.css": {Data: []byte("body {}")},
+		"static/js/code.js":       {Data: []byte("copy()")},
+	}
+
+	m, err := newManifest(fsys)
+	if err != nil {
+		t.Fatalf("new manifest: %s", err)
+	}
+	if len(m.fingerprinted) != 2 || len(m.original) != 2 {
+		t.Fatalf("every file should be fingerprinted, got: %v", m.fingerprinted)
+	}
+	for url, fingerprinted := range m.fingerprinted {
+		if fingerprinted != fingerprint(url, fsys[url[1:]].Data) {
+			t.Errorf("%s should be fingerprinted by its content, got: %s", url, fingerprinted)
+		}
+		if m.original[fingerprinted] != url {
+			t.Errorf("%s should map back to %s, got: %s", fingerprinted, url, m.original[fingerprinted])
+		}
+	}
+}
+
+func TestURL(t *testing.T) {
+	css := URL("/static/fonts/fonts.css")
+	if css == "/static/fonts/fonts.css" || !IsFingerprinted(css) {
+		t.Errorf("embedded files should resolve to their fingerprinted url, got: %s", css)
+	}
+	if IsFingerprinted("/static/fonts/fonts.css") {
+		t.Error("original urls should not be reported as fingerprinted")
+	}
+	if got := URL("/static/missing.css"); got != "/static/missing.css" {
+		t.Errorf("urls of files that aren't embedded should be returned as is, got: %s", got)
+	}
+}
+
+func TestETag(t *testing.T) {
+	etag, ok := ETag("/static/fonts/fonts.css")
+	if !ok || !regexp.MustCompile(`^"[0-9a-f]{32}"$`).MatchString(etag) {
+		t.Errorf("embedded files should have a strong etag, got: %s, %t", etag, ok)
+	}
+	if fingerprinted, _ := ETag(URL("/static/fonts/fonts.css")); fingerprinted != etag {
+		t.Errorf("fingerprinted urls should share the etag of the file, got: %s", fingerprinted)
+	}
+	if other, _ := ETag("/static/js/code.js"); other == etag {
+		t.Error("files with different content should have different etags")
+	}
+	if _, ok := ETag("/static/missing.css"); ok {
+		t.Error("files that aren't embedded should have no etag")
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	want, err := files.ReadFile("static/fonts/fonts.css")
+	if err != nil {
+		t.Fatalf("read embedded file: %s", err)
+	}
+
+	a := New()
+	for _, url := range []string{"/static/fonts/fonts.css", URL("/static/fonts/fonts.css")} {
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s should be served, got status: %d", url, rec.Code)
+		}
+		if !bytes.Equal(rec.Body.Bytes(), want) {
+			t.Errorf("%s should serve the embedded file", url)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "text/css; charset=utf-8" {
+			t.Errorf("%s content type should be detected from the original name, got: %s", url, ct)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/fonts/fonts.000000000000.css", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown fingerprints should not be found, got status: %d", rec.Code)
+	}
+}
+
+func TestRecursiveCopy(t *testing.T) {
+	target := t.TempDir()
+	if err := New().RecursiveCopy(target, 0777); err != nil {
+		t.Fatalf("recursive copy: %s", err)
+	}
+
+	want, err := files.ReadFile("static/js/code.js")
+	if err != nil {
+		t.Fatalf("read embedded file: %s", err)
+	}
+	for _, url := range []string{"/static/js/code.js", URL("/static/js/code.js")} {
+		got, err := os.ReadFile(filepath.Join(target, filepath.FromSlash(url)))
+		if err != nil {
+			t.Fatalf("%s should be copied: %s", url, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s should be a copy of the embedded file", url)
+		}
+	}
+}
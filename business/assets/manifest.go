@@ -0,0 +1,104 @@
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// hashLength is the number of hex characters of the content hash in fingerprinted names
+const hashLength = 12
+
+// Manifest maps the URLs of embedded files to fingerprinted URLs holding a hash of their content, fingerprinted URLs
+// change whenever the content does so they can be cached forever
+type Manifest struct {
+	// fingerprinted maps URLs to fingerprinted URLs, e.g. /static/css/tailwind.css to /static/css/tailwind.0123456789ab.css
+	fingerprinted map[string]string
+	// original maps fingerprinted URLs back to URLs
+	original map[string]string
+	// etags maps URLs to the strong entity tag of their content, a hash of it
+	etags map[string]string
+}
+
+// manifest is the Manifest of the embedded files, it's computed once at startup
+var manifest = mustManifest(files)
+
+// newManifest computes the Manifest of every file in fsys
+func newManifest(fsys fs.FS) (*Manifest, error) {
+	m := &Manifest{
+		fingerprinted: make(map[string]string),
+		original:      make(map[string]string),
+		etags:         make(map[string]string),
+	}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("path %s walkdir: %w", name, err)
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", name, err)
+		}
+
+		url := "/" + name
+		fingerprinted := fingerprint(url, content)
+		m.fingerprinted[url] = fingerprinted
+		m.original[fingerprinted] = url
+		sum := sha256.Sum256(content)
+		m.etags[url] = `"` + hex.EncodeToString(sum[:16]) + `"`
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fs walkdir: %w", err)
+	}
+
+	return m, nil
+}
+
+// mustManifest is newManifest for embedded files, they are part of the binary so failing to read them is a bug
+func mustManifest(fsys fs.FS) *Manifest {
+	m, err := newManifest(fsys)
+	if err != nil {
+		panic(fmt.Sprintf("assets: compute manifest: %s", err))
+	}
+	return m
+}
+
+// fingerprint inserts the content hash before the extension of url
+func fingerprint(url string, content []byte) string {
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])[:hashLength]
+
+	ext := path.Ext(url)
+	return fmt.Sprintf("%s.%s%s", strings.TrimSuffix(url, ext), hash, ext)
+}
+
+// URL resolves the URL of an embedded file, e.g. /static/css/tailwind.css, to its fingerprinted URL,
+// URLs of files that aren't embedded are returned as is
+func URL(url string) string {
+	if fingerprinted, ok := manifest.fingerprinted[url]; ok {
+		return fingerprinted
+	}
+	return url
+}
+
+// IsFingerprinted reports whether url is the fingerprinted URL of an embedded file
+func IsFingerprinted(url string) bool {
+	_, ok := manifest.original[url]
+	return ok
+}
+
+// ETag returns the strong entity tag of the embedded file at url or its fingerprinted URL, false if it isn't embedded
+func ETag(url string) (string, bool) {
+	if original, ok := manifest.original[url]; ok {
+		url = original
+	}
+	etag, ok := manifest.etags[url]
+	return etag, ok
+}
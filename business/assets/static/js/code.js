@@ -0,0 +1,13 @@
+// copy buttons of code blocks copy the code without line numbers
+document.addEventListener('click', (event) => {
+    const button = event.target.closest('[data-copy-code]');
+    if (button == null) {
+        return;
+    }
+
+    const code = button.closest('[data-code-block]').querySelector('[data-code]');
+    navigator.clipboard.writeText(code.textContent).then(() => {
+        button.textContent = 'COPIED';
+        setTimeout(() => button.textContent = 'COPY', 2000);
+    });
+});
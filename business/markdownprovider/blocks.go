@@ -0,0 +1,219 @@
+package markdownprovider
+
+import (
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/so-heil/goblog/business/templates/components/elements"
+	"github.com/so-heil/goblog/business/templates/components/toc"
+	"github.com/yuin/goldmark/ast"
+)
+
+// components renders markdown block nodes as templ components using the same elements as notion blocks
+func components(nodes []ast.Node, source []byte) []templ.Component {
+	var comps []templ.Component
+	for _, node := range nodes {
+		if c := component(node, source); c != nil {
+			comps = append(comps, c)
+		}
+	}
+	return comps
+}
+
+// headings sets the id attribute of every heading under nodes in page order and returns the h1 to h3 headings
+// as table of contents headings, lower level headings are rendered as h3 but are left out of the table of contents
+func headings(nodes []ast.Node, source []byte, slugger *toc.Slugger) []toc.Heading {
+	var hs []toc.Heading
+	for _, node := range nodes {
+		_ = ast.Walk(node, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+			h, ok := node.(*ast.Heading)
+			if !ok || !entering {
+				return ast.WalkContinue, nil
+			}
+
+			title := plainText(h, source)
+			id := slugger.Slug(title)
+			h.SetAttributeString("id", []byte(id))
+			if h.Level <= 3 {
+				hs = append(hs, toc.Heading{ID: id, Title: title, Level: h.Level})
+			}
+			return ast.WalkSkipChildren, nil
+		})
+	}
+	return hs
+}
+
+// anchor returns the id attribute set on heading by headings
+func anchor(heading *ast.Heading) string {
+	if id, ok := heading.AttributeString("id"); ok {
+		if b, ok := id.([]byte); ok {
+			return string(b)
+		}
+	}
+	return ""
+}
+
+// children returns the child nodes of node
+func children(node ast.Node) []ast.Node {
+	var nodes []ast.Node
+	for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+		nodes = append(nodes, c)
+	}
+	return nodes
+}
+
+// component renders a single markdown block node, it returns nil for nodes that have no representation e.g. raw HTML
+func component(node ast.Node, source []byte) templ.Component {
+	switch n := node.(type) {
+	case *ast.Heading:
+		id := anchor(n)
+		switch n.Level {
+		case 1:
+			return elements.Heading1(id, richText(n, source))
+		case 2:
+			return elements.Heading2(id, richText(n, source))
+		default:
+			return elements.Heading3(id, richText(n, source))
+		}
+	case *ast.Paragraph, *ast.TextBlock:
+		// an image on its own line is a block image like notion image blocks
+		if img, ok := n.FirstChild().(*ast.Image); ok && n.ChildCount() == 1 {
+			return elements.Image(string(img.Destination), plainText(img, source))
+		}
+		return elements.Paragraph(richText(n, source))
+	case *ast.List:
+		items := make([]templ.Component, 0, n.ChildCount())
+		for item := n.FirstChild(); item != nil; item = item.NextSibling() {
+			items = append(items, listItem(item, source))
+		}
+		if n.IsOrdered() {
+			return elements.NumberedList(items)
+		}
+		return elements.BulletedList(items)
+	case *ast.Blockquote:
+		nodes := children(n)
+		if len(nodes) == 0 {
+			return elements.Quote(nil, nil)
+		}
+		return elements.Quote(richText(nodes[0], source), components(nodes[1:], source))
+	case *ast.FencedCodeBlock:
+		return elements.Code(lines(n, source), string(n.Language(source)), codeOptions(n, source))
+	case *ast.CodeBlock:
+		return elements.Code(lines(n, source), "", elements.CodeOptions{})
+	}
+
+	return nil
+}
+
+// listItem renders a list item, the first text of the item is its content and the rest (e.g. nested lists) its children
+func listItem(item ast.Node, source []byte) templ.Component {
+	nodes := children(item)
+	if len(nodes) == 0 {
+		return elements.ListItem(nil, nil)
+	}
+
+	switch nodes[0].(type) {
+	case *ast.Paragraph, *ast.TextBlock:
+		return elements.ListItem(richText(nodes[0], source), components(nodes[1:], source))
+	}
+	return elements.ListItem(nil, components(nodes, source))
+}
+
+// richText converts the inline nodes of n to rich text, nested emphasis and links compose their annotations
+func richText(n ast.Node, source []byte) elements.RichText {
+	var rt elements.RichText
+	var walk func(n ast.Node, t elements.Text)
+	walk = func(n ast.Node, t elements.Text) {
+		for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+			switch inline := c.(type) {
+			case *ast.Emphasis:
+				annotated := t
+				if inline.Level >= 2 {
+					annotated.Bold = true
+				} else {
+					annotated.Italic = true
+				}
+				walk(inline, annotated)
+			case *ast.Link:
+				link := string(inline.Destination)
+				linked := t
+				linked.Link = &link
+				walk(inline, linked)
+			case *ast.AutoLink:
+				link := string(inline.URL(source))
+				linked := t
+				linked.Link = &link
+				linked.Content = string(inline.Label(source))
+				rt = append(rt, linked)
+			case *ast.CodeSpan:
+				code := t
+				code.Code = true
+				code.Content = inlineText(inline, source)
+				rt = append(rt, code)
+			case *ast.Text, *ast.String:
+				text := t
+				text.Content = inlineText(inline, source)
+				rt = append(rt, text)
+			default:
+				walk(inline, t)
+			}
+		}
+	}
+	walk(n, elements.Text{})
+
+	// the trailing line break of the last line is not part of the text
+	if len(rt) > 0 {
+		rt[len(rt)-1].Content = strings.TrimRight(rt[len(rt)-1].Content, " ")
+	}
+	return rt
+}
+
+// plainText is the inline text of n without surrounding spaces
+func plainText(n ast.Node, source []byte) string {
+	return strings.TrimSpace(inlineText(n, source))
+}
+
+// inlineText concatenates the text of every inline node under n, line breaks become spaces
+func inlineText(n ast.Node, source []byte) string {
+	var s strings.Builder
+	_ = ast.Walk(n, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch t := node.(type) {
+		case *ast.Text:
+			s.Write(t.Segment.Value(source))
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				s.WriteString(" ")
+			}
+		case *ast.String:
+			s.Write(t.Value)
+		case *ast.AutoLink:
+			s.Write(t.Label(source))
+		}
+		return ast.WalkContinue, nil
+	})
+	return s.String()
+}
+
+// codeOptions parses the options following the language in the info string of a fenced code block,
+// e.g. ```go {linenos hl_lines=2-3}
+func codeOptions(n *ast.FencedCodeBlock, source []byte) elements.CodeOptions {
+	if n.Info == nil {
+		return elements.CodeOptions{}
+	}
+	_, options, _ := strings.Cut(string(n.Info.Segment.Value(source)), " ")
+	return elements.ParseCodeOptions(options)
+}
+
+// lines returns the raw lines of a code block
+func lines(n ast.Node, source []byte) string {
+	var s strings.Builder
+	segments := n.Lines()
+	for i := 0; i < segments.Len(); i++ {
+		segment := segments.At(i)
+		s.Write(segment.Value(source))
+	}
+	return s.String()
+}
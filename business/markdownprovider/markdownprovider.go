@@ -0,0 +1,173 @@
+// Package markdownprovider is an article provider that reads articles and their content from a directory of markdown files
+// with YAML front matter
+package markdownprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+
+	"github.com/so-heil/goblog/business/articles"
+	"github.com/so-heil/goblog/business/pages"
+	"github.com/so-heil/goblog/business/templates/components/elements"
+	"github.com/so-heil/goblog/business/templates/components/toc"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Provider implements pages.Provider giving access to articles written as markdown files in a file system
+type Provider struct {
+	fsys fs.FS
+}
+
+// NewProvider creates a Provider reading every .md file in fsys, e.g. os.DirFS of the articles directory
+func NewProvider(fsys fs.FS) *Provider {
+	return &Provider{fsys: fsys}
+}
+
+// files reads and parses every markdown file in the provider file system
+func (mp *Provider) files(ctx context.Context) ([]*markdownFile, error) {
+	var files []*markdownFile
+	err := fs.WalkDir(mp.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("path %s walkdir: %w", p, err)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(p) != ".md" {
+			return nil
+		}
+
+		mf, err := mp.file(p)
+		if err != nil {
+			return err
+		}
+		files = append(files, mf)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fs walkdir: %w", err)
+	}
+
+	return files, nil
+}
+
+// file reads and parses the markdown file at p
+func (mp *Provider) file(p string) (*markdownFile, error) {
+	content, err := fs.ReadFile(mp.fsys, p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("read %s: %w", p, articles.ErrArticleNotFound)
+		}
+		return nil, fmt.Errorf("read %s: %w", p, err)
+	}
+
+	info, err := fs.Stat(mp.fsys, p)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", p, err)
+	}
+
+	return parseFile(p, info.ModTime(), content)
+}
+
+// Articles returns the files with article type, newest first
+func (mp *Provider) Articles(ctx context.Context) ([]articles.Article, error) {
+	files, err := mp.files(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("retiriving articles: %w", err)
+	}
+
+	var atcls []articles.Article
+	for _, mf := range files {
+		if mf.isArticle() {
+			atcls = append(atcls, mf.toArticle())
+		}
+	}
+
+	sort.SliceStable(atcls, func(i, j int) bool {
+		return atcls[i].WrittenAt.After(atcls[j].WrittenAt)
+	})
+
+	return atcls, nil
+}
+
+// AboutPage returns the file with the about page slug
+func (mp *Provider) AboutPage(ctx context.Context) (pages.About, error) {
+	files, err := mp.files(ctx)
+	if err != nil {
+		return pages.About{}, fmt.Errorf("retiriving pages: %w", err)
+	}
+
+	var about []*markdownFile
+	for _, mf := range files {
+		if mf.frontMatter.Slug == pages.AboutPageID {
+			about = append(about, mf)
+		}
+	}
+	if len(about) != 1 {
+		return pages.About{}, fmt.Errorf("there should be exactly 1 file with %s slug, found %d", pages.AboutPageID, len(about))
+	}
+
+	aboutArticle := about[0].toArticle()
+	return pages.About{
+		ID:             aboutArticle.ID,
+		Title:          aboutArticle.Title,
+		SubTitle:       aboutArticle.Excerpt,
+		LastEditedTime: aboutArticle.LastEditedTime,
+	}, nil
+}
+
+// Content parses the markdown body of the file with id as its path and creates the corresponding pages.SectionBlock
+// for each thematic break (---) delimited section
+func (mp *Provider) Content(ctx context.Context, id string) ([]pages.SectionBlock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	mf, err := mp.file(id)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := goldmark.DefaultParser().Parse(text.NewReader(mf.body))
+
+	var section []ast.Node
+	var sblock []pages.SectionBlock
+	var sectionTitle string
+	slugger := toc.NewSlugger()
+	addSection := func() {
+		// ids are set before rendering so heading components get them
+		sectionHeadings := headings(section, mf.body, slugger)
+		sblock = append(sblock, pages.SectionBlock{
+			Title:     sectionTitle,
+			Component: elements.Section(components(section, mf.body)),
+			Headings:  sectionHeadings,
+		})
+		section = nil
+		sectionTitle = ""
+	}
+
+	for node := doc.FirstChild(); node != nil; node = node.NextSibling() {
+		switch n := node.(type) {
+		case *ast.ThematicBreak:
+			addSection()
+			continue
+		case *ast.Heading:
+			if n.Level == 2 && sectionTitle == "" {
+				sectionTitle = plainText(n, mf.body)
+			}
+		}
+		section = append(section, node)
+	}
+
+	if len(section) > 0 {
+		addSection()
+	}
+
+	return sblock, nil
+}
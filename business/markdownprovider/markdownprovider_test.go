@@ -0,0 +1,166 @@
+package markdownprovider_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/so-heil/goblog/business/articles"
+	"github.com/so-heil/goblog/business/markdownprovider"
+)
+
+const firstArticle = `---
+title: First Article
+slug: first-article
+excerpt: The first article
+written_at: 2023-11-20
+type: article
+tags: [Go, Testing]
+---
+## Introduction
+
+Plain, **bold**, *italic*, ` + "`code`" + ` and [a link](https://go.dev).
+A **[bold link](https://example.com)** here.
+
+- First item
+  - Nested item
+- Second item
+
+1. Step one
+2. Step two
+
+### Details
+
+---
+
+## Details
+
+` + "```go {linenos hl_lines=1}\nfmt.Println(\"hello\")\n```" + `
+
+> A quote
+
+![A gopher](/static/images/gopher.svg)
+`
+
+const secondArticle = `---
+title: Second Article
+slug: second-article
+written_at: 2023-12-01
+type: article
+status: Scheduled
+publish_at: 2023-12-02T08:00:00Z
+---
+Second article content
+`
+
+const about = `---
+title: Soheil Ansari
+slug: about_page
+excerpt: Software engineer
+type: page
+---
+About me
+`
+
+func TestProvider(t *testing.T) {
+	ctx := context.Background()
+	modTime := time.Date(2023, 11, 21, 10, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"first.md":           {Data: []byte(firstArticle), ModTime: modTime},
+		"2023/second.md":     {Data: []byte(secondArticle), ModTime: modTime},
+		"about.md":           {Data: []byte(about), ModTime: modTime},
+		"notes.txt":          {Data: []byte("not markdown")},
+		"no-front-matter.md": {Data: []byte("# Just markdown")},
+	}
+	p := markdownprovider.NewProvider(fsys)
+
+	atcls, err := p.Articles(ctx)
+	if err != nil {
+		t.Fatalf("should read articles: %s", err)
+	}
+	if len(atcls) != 2 {
+		t.Fatalf("should return the 2 article files, got: %d", len(atcls))
+	}
+	if atcls[0].Slug != "second-article" {
+		t.Errorf("articles should be sorted newest first, first is: %s", atcls[0].Slug)
+	}
+	if atcls[0].Status != articles.StatusScheduled || atcls[0].PublishAt.Format(time.RFC3339) != "2023-12-02T08:00:00Z" {
+		t.Errorf("article publishing should be read from front matter, got: %s at %s", atcls[0].Status, atcls[0].PublishAt)
+	}
+
+	sample := atcls[1]
+	if sample.Title != "First Article" || sample.Excerpt != "The first article" || sample.ID != "first.md" {
+		t.Errorf("article should be read from front matter, got: %+v", sample)
+	}
+	if strings.Join(sample.Tags, ",") != "Go,Testing" {
+		t.Errorf("article tags should be read from front matter, got: %v", sample.Tags)
+	}
+	if sample.WrittenAt.Format("2006-01-02") != "2023-11-20" || !sample.LastEditedTime.Equal(modTime) {
+		t.Errorf("article dates should be read from front matter and file, got: %+v", sample)
+	}
+
+	aboutPage, err := p.AboutPage(ctx)
+	if err != nil {
+		t.Fatalf("should read about page: %s", err)
+	}
+	if aboutPage.ID != "about.md" || aboutPage.SubTitle != "Software engineer" {
+		t.Errorf("about page should be read from front matter, got: %+v", aboutPage)
+	}
+
+	blocks, err := p.Content(ctx, sample.ID)
+	if err != nil {
+		t.Fatalf("should get article content: %s", err)
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("article should have 2 thematic break delimited sections, got: %d", len(blocks))
+	}
+	if blocks[0].Title != "Introduction" || blocks[1].Title != "Details" {
+		t.Errorf("sections should be titled by their first heading, got: %q, %q", blocks[0].Title, blocks[1].Title)
+	}
+
+	if hs := blocks[0].Headings; len(hs) != 2 || hs[1].ID != "details" || hs[1].Level != 3 {
+		t.Errorf("section headings should be listed with their anchors and levels, got: %+v", hs)
+	}
+
+	buf := new(bytes.Buffer)
+	for _, b := range blocks {
+		if err := b.Component.Render(ctx, buf); err != nil {
+			t.Fatalf("render section: %s", err)
+		}
+	}
+	html := buf.String()
+
+	for _, want := range []string{
+		`<h2 id="introduction" class="group scroll-mt-28">Introduction<a`,
+		`<h3 id="details" class="group scroll-mt-28">Details<a`,
+		// anchors are unique in the page even when headings repeat across sections
+		`<h2 id="details-1" class="group scroll-mt-28">Details<a`,
+		`href="#details-1" aria-label="Permalink"`,
+		"Plain, <strong>bold</strong>",
+		"<i>italic</i>",
+		"<code>code</code>",
+		`<a href="https://go.dev">a link</a>`,
+		`<a href="https://example.com"><strong>bold link</strong></a>`,
+		"Nested item",
+		"<ol><li>Step one</li><li>Step two</li></ol>",
+		// highlighted at render time with line numbers in a pre of their own
+		`>Println</span>`,
+		`&#34;hello&#34;</span>`,
+		`aria-hidden="true"`,
+		"data-copy-code",
+		"A quote",
+		`<img src="/static/images/gopher.svg" alt="A gopher">`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("rendered content should contain %q\n%s", want, html)
+		}
+	}
+
+	if _, err := p.Content(ctx, "missing.md"); !errors.Is(err, articles.ErrArticleNotFound) {
+		t.Errorf("missing files should not be found, got: %v", err)
+	}
+}
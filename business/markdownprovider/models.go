@@ -0,0 +1,92 @@
+package markdownprovider
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/so-heil/goblog/business/articles"
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatterDelimiter opens and closes the YAML front matter at the top of a markdown file
+const frontMatterDelimiter = "---"
+
+type frontMatter struct {
+	Title     string   `yaml:"title"`
+	Slug      string   `yaml:"slug"`
+	Excerpt   string   `yaml:"excerpt"`
+	WrittenAt string   `yaml:"written_at"`
+	Type      string   `yaml:"type"`
+	Tags      []string `yaml:"tags"`
+	Status    string   `yaml:"status"`
+	PublishAt string   `yaml:"publish_at"`
+}
+
+// markdownFile is a parsed markdown file, path is relative to the provider directory and used as the article ID
+type markdownFile struct {
+	path        string
+	modTime     time.Time
+	frontMatter frontMatter
+	body        []byte
+}
+
+// parseFile splits content into its front matter and markdown body, a file without front matter has an empty front matter
+func parseFile(path string, modTime time.Time, content []byte) (*markdownFile, error) {
+	mf := markdownFile{path: path, modTime: modTime, body: content}
+
+	content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	if !bytes.HasPrefix(content, []byte(frontMatterDelimiter+"\n")) {
+		return &mf, nil
+	}
+
+	rest := content[len(frontMatterDelimiter)+1:]
+	raw, body, found := bytes.Cut(rest, []byte("\n"+frontMatterDelimiter+"\n"))
+	if !found {
+		// front matter closed at the end of the file
+		if raw, found = bytes.CutSuffix(rest, []byte("\n"+frontMatterDelimiter)); !found {
+			return nil, fmt.Errorf("front matter of %s is not closed", path)
+		}
+	}
+
+	if err := yaml.Unmarshal(raw, &mf.frontMatter); err != nil {
+		return nil, fmt.Errorf("decode front matter of %s: %w", path, err)
+	}
+	mf.body = body
+
+	return &mf, nil
+}
+
+// isArticle reports whether the file is a blog article as opposed to a special page
+func (mf *markdownFile) isArticle() bool {
+	return strings.EqualFold(mf.frontMatter.Type, "article")
+}
+
+func (mf *markdownFile) toArticle() articles.Article {
+	article := articles.Article{
+		ID:             mf.path,
+		LastEditedTime: mf.modTime,
+		Title:          mf.frontMatter.Title,
+		Excerpt:        mf.frontMatter.Excerpt,
+		Slug:           mf.frontMatter.Slug,
+		Tags:           mf.frontMatter.Tags,
+		Status:         articles.ParseStatus(mf.frontMatter.Status),
+	}
+
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if wa, err := time.Parse(layout, mf.frontMatter.WrittenAt); err == nil {
+			article.WrittenAt = wa
+			break
+		}
+	}
+
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if pa, err := time.Parse(layout, mf.frontMatter.PublishAt); err == nil {
+			article.PublishAt = pa
+			break
+		}
+	}
+
+	return article
+}
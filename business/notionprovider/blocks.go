@@ -0,0 +1,335 @@
+package notionprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/so-heil/goblog/business/templates/components/elements"
+	"github.com/so-heil/goblog/business/templates/components/toc"
+	"github.com/so-heil/goblog/foundation/notion"
+)
+
+// maxChildrenDepth is how deep nested blocks are followed, blocks below it are rendered without children
+const maxChildrenDepth = 5
+
+// maxChildrenRequests is the number of children requests sent to notion concurrently
+const maxChildrenRequests = 3
+
+// blockChildren fetches every child block of the block (or page) with id
+func (np *Provider) blockChildren(ctx context.Context, id string) ([]notionBlock, error) {
+	results, err := notion.RequestAll[notionBlock](ctx, np.notionClient, http.MethodGet, fmt.Sprintf("/blocks/%s/children", id), nil)
+	if err != nil {
+		return nil, fmt.Errorf("retiriving block with id %s childern: %w", id, err)
+	}
+	return results, nil
+}
+
+// fetchChildren walks the block tree level by level filling children of blocks that have any,
+// children of a level are requested concurrently with at most maxChildrenRequests in flight
+func (np *Provider) fetchChildren(ctx context.Context, blocks []*notionBlock, depth int) error {
+	if depth >= maxChildrenDepth {
+		return nil
+	}
+
+	var parents []*notionBlock
+	for _, nblock := range blocks {
+		if nblock.HasChildren {
+			parents = append(parents, nblock)
+		}
+	}
+	if len(parents) == 0 {
+		return nil
+	}
+
+	// counting semaphore to control the number of concurrent requests
+	sem := make(chan struct{}, maxChildrenRequests)
+	errs := make(chan error, len(parents))
+	for _, parent := range parents {
+		go func(parent *notionBlock) {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			id := parent.ID
+			// a duplicate synced block keeps its content in the original block
+			if parent.SyncedBlock != nil && parent.SyncedBlock.SyncedFrom != nil {
+				id = parent.SyncedBlock.SyncedFrom.BlockID
+			}
+
+			children, err := np.blockChildren(ctx, id)
+			parent.children = children
+			errs <- err
+		}(parent)
+	}
+
+	var fetchErr error
+	for range parents {
+		if err := <-errs; err != nil {
+			fetchErr = errors.Join(fetchErr, err)
+		}
+	}
+	if fetchErr != nil {
+		return fetchErr
+	}
+
+	var next []*notionBlock
+	for _, parent := range parents {
+		for i := range parent.children {
+			next = append(next, &parent.children[i])
+		}
+	}
+
+	return np.fetchChildren(ctx, next, depth+1)
+}
+
+// nestsChildren are the block types whose component renders the block children itself
+var nestsChildren = map[string]bool{
+	"bulleted_list_item": true,
+	"numbered_list_item": true,
+	"to_do":              true,
+	"quote":              true,
+	"toggle":             true,
+	"callout":            true,
+	"column_list":        true,
+	"column":             true,
+	"table":              true,
+}
+
+// withoutComponent are the block types that are deliberately not rendered, dividers split sections and
+// notion navigation blocks are replaced by the website's own navigation
+var withoutComponent = map[string]bool{
+	"divider":           true,
+	"table_of_contents": true,
+	"breadcrumb":        true,
+}
+
+// components renders blocks as templ components, consecutive list items are grouped in a single list
+func components(blocks []notionBlock) []templ.Component {
+	var comps []templ.Component
+	var listItems []templ.Component
+	var listType string
+	endList := func() {
+		switch listType {
+		case "bulleted_list_item":
+			comps = append(comps, elements.BulletedList(listItems))
+		case "numbered_list_item":
+			comps = append(comps, elements.NumberedList(listItems))
+		}
+		listItems = nil
+		listType = ""
+	}
+
+	for _, nblock := range blocks {
+		if nblock.Type != listType {
+			endList()
+		}
+
+		switch nblock.Type {
+		case "bulleted_list_item":
+			listType = nblock.Type
+			listItems = append(listItems, elements.ListItem(nblock.BulletedListItem.RichText.toRichText(), components(nblock.children)))
+			continue
+		case "numbered_list_item":
+			listType = nblock.Type
+			listItems = append(listItems, elements.ListItem(nblock.NumberedListItem.RichText.toRichText(), components(nblock.children)))
+			continue
+		case "synced_block":
+			// synced blocks have no representation of their own, their content is rendered in place
+			comps = append(comps, components(nblock.children)...)
+			continue
+		}
+
+		if c := component(nblock); c != nil {
+			comps = append(comps, c)
+		}
+		// children of blocks that have no place for them are indented below the block
+		if len(nblock.children) > 0 && !nestsChildren[nblock.Type] {
+			comps = append(comps, elements.Indented(components(nblock.children)))
+		}
+	}
+	endList()
+
+	return comps
+}
+
+// component renders a single block and its children, it returns nil for blocks that are deliberately not rendered
+// and an unsupported placeholder for block types it doesn't know
+func component(nblock notionBlock) templ.Component {
+	switch nblock.Type {
+	case "heading_1":
+		return elements.Heading1(nblock.anchor, nblock.Heading1.RichText.toRichText())
+	case "heading_2":
+		return elements.Heading2(nblock.anchor, nblock.Heading2.RichText.toRichText())
+	case "heading_3":
+		return elements.Heading3(nblock.anchor, nblock.Heading3.RichText.toRichText())
+	case "quote":
+		return elements.Quote(nblock.Quote.RichText.toRichText(), components(nblock.children))
+	case "to_do":
+		return elements.ToDo(nblock.ToDo.RichText.toRichText(), nblock.ToDo.Checked, components(nblock.children))
+	case "toggle":
+		return elements.Toggle(nblock.Toggle.RichText.toRichText(), components(nblock.children))
+	case "callout":
+		var icon string
+		if nblock.Callout.Icon != nil && nblock.Callout.Icon.Type == "emoji" {
+			icon = nblock.Callout.Icon.Emoji
+		}
+		return elements.Callout(icon, nblock.Callout.RichText.toRichText(), components(nblock.children))
+	case "column_list":
+		return elements.ColumnList(components(nblock.children))
+	case "column":
+		return elements.Column(components(nblock.children))
+	case "table":
+		rows := make([][]elements.RichText, 0, len(nblock.children))
+		for _, row := range nblock.children {
+			if row.TableRow == nil {
+				continue
+			}
+			cells := make([]elements.RichText, len(row.TableRow.Cells))
+			for i, cell := range row.TableRow.Cells {
+				cells[i] = cell.toRichText()
+			}
+			rows = append(rows, cells)
+		}
+		return elements.Table(rows, nblock.Table.HasColumnHeader, nblock.Table.HasRowHeader)
+	case "equation":
+		return elements.Equation(nblock.Equation.Expression, true)
+	case "bookmark":
+		return elements.Bookmark(nblock.Bookmark.Url, nblock.Bookmark.Caption.toRichText())
+	case "link_preview":
+		return elements.Bookmark(nblock.LinkPreview.Url, nil)
+	case "embed":
+		return elements.Embed(nblock.Embed.Url, nblock.Embed.Caption.toRichText())
+	case "image":
+		return elements.Image(nblock.Image.url(), nblock.Image.Caption.toString())
+	case "video":
+		if src, ok := videoEmbedURL(nblock.Video.url()); ok {
+			return elements.Embed(src, nblock.Video.Caption.toRichText())
+		}
+		return elements.Video(nblock.Video.url(), nblock.Video.Caption.toRichText())
+	case "audio":
+		return elements.Audio(nblock.Audio.url(), nblock.Audio.Caption.toRichText())
+	case "file":
+		return elements.File(nblock.File.url(), fileName(nblock.File))
+	case "pdf":
+		return elements.PDF(nblock.PDF.url(), nblock.PDF.Caption.toRichText())
+	case "child_page":
+		return elements.ChildPage(nblock.ChildPage.Title)
+	case "paragraph":
+		return elements.Paragraph(nblock.Paragraph.RichText.toRichText())
+	case "code":
+		// options like line numbers are set in the code caption as notion code blocks have no such settings
+		return elements.Code(nblock.Code.RichText.toString(), codeLanguage(nblock.Code.Language), elements.ParseCodeOptions(nblock.Code.Caption.toString()))
+	}
+
+	if withoutComponent[nblock.Type] {
+		return nil
+	}
+
+	log.Printf("notionprovider: unsupported block type %q, block id: %s\n", nblock.Type, nblock.ID)
+	return elements.Unsupported(nblock.Type)
+}
+
+// codeLanguages maps notion code languages to the lexers highlighting them where their names differ,
+// other languages are lexer names or aliases already
+var codeLanguages = map[string]string{
+	"plain text":     "plaintext",
+	"markup":         "html",
+	"flow":           "javascript",
+	"java/c/c++/c#":  "java",
+	"visual basic":   "vb.net",
+	"webassembly":    "wast",
+	"livescript":     "plaintext",
+	"mermaid":        "plaintext",
+	"notion formula": "plaintext",
+}
+
+// codeLanguage returns the lexer name of a notion code language
+func codeLanguage(language string) string {
+	if lexer, ok := codeLanguages[language]; ok {
+		return lexer
+	}
+	return language
+}
+
+// fileName is the name a file block is shown with, falling back to its caption and then the last part of its url
+func fileName(f *file) string {
+	if f.Name != "" {
+		return f.Name
+	}
+	if caption := f.Caption.toString(); caption != "" {
+		return caption
+	}
+
+	u, err := url.Parse(f.url())
+	if err != nil {
+		return f.url()
+	}
+	return path.Base(u.Path)
+}
+
+// videoEmbedURL converts links to videos on video platforms to their embeddable player url
+func videoEmbedURL(src string) (string, bool) {
+	u, err := url.Parse(src)
+	if err != nil {
+		return "", false
+	}
+
+	switch strings.TrimPrefix(u.Host, "www.") {
+	case "youtube.com":
+		if id := u.Query().Get("v"); id != "" {
+			return "https://www.youtube.com/embed/" + id, true
+		}
+	case "youtu.be":
+		return "https://www.youtube.com/embed" + u.Path, true
+	case "vimeo.com":
+		return "https://player.vimeo.com/video" + u.Path, true
+	}
+
+	return "", false
+}
+
+// headings sets the anchors of heading blocks and their nested heading children in page order
+// and returns them as table of contents headings
+func headings(blocks []notionBlock, slugger *toc.Slugger) []toc.Heading {
+	var hs []toc.Heading
+	for i := range blocks {
+		nblock := &blocks[i]
+		var level int
+		var title string
+		switch nblock.Type {
+		case "heading_1":
+			level, title = 1, nblock.Heading1.RichText.toString()
+		case "heading_2":
+			level, title = 2, nblock.Heading2.RichText.toString()
+		case "heading_3":
+			level, title = 3, nblock.Heading3.RichText.toString()
+		}
+		if level != 0 {
+			nblock.anchor = slugger.Slug(title)
+			hs = append(hs, toc.Heading{ID: nblock.anchor, Title: title, Level: level})
+		}
+		hs = append(hs, headings(nblock.children, slugger)...)
+	}
+	return hs
+}
+
+// hostedFiles returns the urls of notion hosted files referenced by blocks and their children,
+// these urls expire so they are reported to be self-hosted
+func hostedFiles(blocks []notionBlock) []string {
+	var urls []string
+	for _, nblock := range blocks {
+		for _, f := range []*file{nblock.Image, nblock.Video, nblock.Audio, nblock.File, nblock.PDF} {
+			if f != nil && f.Type == "file" && f.File.Url != "" {
+				urls = append(urls, f.File.Url)
+			}
+		}
+		urls = append(urls, hostedFiles(nblock.children)...)
+	}
+	return urls
+}
@@ -5,13 +5,32 @@ import (
 	"time"
 
 	"github.com/so-heil/goblog/business/articles"
+	"github.com/so-heil/goblog/business/templates/components/elements"
 )
 
 type (
-	textContent struct {
-		Text string `json:"plain_text"`
+	// richText is a span of notion rich text, either a text, a mention or an inline equation
+	richText struct {
+		Type        string  `json:"type"`
+		PlainText   string  `json:"plain_text"`
+		Href        *string `json:"href"`
+		Annotations struct {
+			Bold          bool   `json:"bold"`
+			Italic        bool   `json:"italic"`
+			Strikethrough bool   `json:"strikethrough"`
+			Underline     bool   `json:"underline"`
+			Code          bool   `json:"code"`
+			Color         string `json:"color"`
+		} `json:"annotations"`
+		Mention *struct {
+			Type string `json:"type"`
+			Date *struct {
+				Start string `json:"start"`
+			} `json:"date"`
+		} `json:"mention"`
+		Equation *equation `json:"equation"`
 	}
-	textContents  []textContent
+	textContents  []richText
 	notionArticle struct {
 		LastEditedTime time.Time `json:"last_edited_time"`
 		ID             string    `json:"id"`
@@ -41,50 +60,116 @@ type (
 					Name string `json:"name"`
 				} `json:"select"`
 			} `json:"Type"`
+			Tags struct {
+				MultiSelect []struct {
+					Name string `json:"name"`
+				} `json:"multi_select"`
+			} `json:"Tags"`
+			// Status is either a status or a select property
+			Status struct {
+				Status *struct {
+					Name string `json:"name"`
+				} `json:"status"`
+				Select *struct {
+					Name string `json:"name"`
+				} `json:"select"`
+			} `json:"Status"`
+			PublishAt struct {
+				Date *struct {
+					Start string `json:"start"`
+				} `json:"date"`
+			} `json:"PublishAt"`
 		} `json:"properties"`
 	}
 	textBlock struct {
 		RichText textContents `json:"rich_text"`
 	}
-	paragraph struct {
-		RichText []struct {
-			Annotations struct {
-				Bold          bool `json:"bold"`
-				Italic        bool `json:"italic"`
-				Strikethrough bool `json:"strikethrough"`
-				Underline     bool `json:"underline"`
-				Code          bool `json:"code"`
-			} `json:"annotations"`
-			PlainText string  `json:"plain_text"`
-			Href      *string `json:"href"`
-		} `json:"rich_text"`
-	}
-	image struct {
+	// file is a notion file object used by image, video, audio, file and pdf blocks,
+	// it is either hosted by notion (File) or an external url (External)
+	file struct {
+		Type    string       `json:"type"`
 		Caption textContents `json:"caption"`
+		Name    string       `json:"name"`
 		File    struct {
 			Url        string    `json:"url"`
 			ExpiryTime time.Time `json:"expiry_time"`
 		} `json:"file"`
+		External struct {
+			Url string `json:"url"`
+		} `json:"external"`
+	}
+	toDo struct {
+		RichText textContents `json:"rich_text"`
+		Checked  bool         `json:"checked"`
+	}
+	callout struct {
+		RichText textContents `json:"rich_text"`
+		Icon     *struct {
+			Type  string `json:"type"`
+			Emoji string `json:"emoji"`
+		} `json:"icon"`
+	}
+	table struct {
+		HasColumnHeader bool `json:"has_column_header"`
+		HasRowHeader    bool `json:"has_row_header"`
+	}
+	tableRow struct {
+		Cells []textContents `json:"cells"`
+	}
+	equation struct {
+		Expression string `json:"expression"`
+	}
+	// link is used by bookmark, embed and link_preview blocks
+	link struct {
+		Url     string       `json:"url"`
+		Caption textContents `json:"caption"`
+	}
+	childPage struct {
+		Title string `json:"title"`
 	}
 	code struct {
-		RichText []struct {
-			Type string `json:"type"`
-			Text struct {
-				Content string `json:"content"`
-			} `json:"text"`
-		} `json:"rich_text"`
-		Language string `json:"language"`
+		RichText textContents `json:"rich_text"`
+		Caption  textContents `json:"caption"`
+		Language string       `json:"language"`
+	}
+	syncedBlock struct {
+		SyncedFrom *struct {
+			BlockID string `json:"block_id"`
+		} `json:"synced_from"`
 	}
 	notionBlock struct {
-		Type             string     `json:"type"`
-		Heading1         *textBlock `json:"heading_1"`
-		Heading2         *textBlock `json:"heading_2"`
-		Heading3         *textBlock `json:"heading_3"`
-		Quote            *textBlock `json:"quote"`
-		Paragraph        *paragraph `json:"paragraph"`
-		Image            *image     `json:"image"`
-		Code             *code      `json:"code"`
-		BulletedListItem *textBlock `json:"bulleted_list_item"`
+		ID               string       `json:"id"`
+		HasChildren      bool         `json:"has_children"`
+		Type             string       `json:"type"`
+		Heading1         *textBlock   `json:"heading_1"`
+		Heading2         *textBlock   `json:"heading_2"`
+		Heading3         *textBlock   `json:"heading_3"`
+		Quote            *textBlock   `json:"quote"`
+		Paragraph        *textBlock   `json:"paragraph"`
+		Image            *file        `json:"image"`
+		Code             *code        `json:"code"`
+		BulletedListItem *textBlock   `json:"bulleted_list_item"`
+		NumberedListItem *textBlock   `json:"numbered_list_item"`
+		ToDo             *toDo        `json:"to_do"`
+		Toggle           *textBlock   `json:"toggle"`
+		Callout          *callout     `json:"callout"`
+		SyncedBlock      *syncedBlock `json:"synced_block"`
+		Table            *table       `json:"table"`
+		TableRow         *tableRow    `json:"table_row"`
+		Equation         *equation    `json:"equation"`
+		Bookmark         *link        `json:"bookmark"`
+		Embed            *link        `json:"embed"`
+		LinkPreview      *link        `json:"link_preview"`
+		Video            *file        `json:"video"`
+		Audio            *file        `json:"audio"`
+		File             *file        `json:"file"`
+		PDF              *file        `json:"pdf"`
+		ChildPage        *childPage   `json:"child_page"`
+
+		// children are the nested blocks of this block, only fetched when HasChildren is set
+		children []notionBlock
+		// anchor is the unique id of a heading block in its page
+		anchor string
 	}
 )
 
@@ -113,13 +198,85 @@ func (na *notionArticle) toArticle() articles.Article {
 		article.Slug = na.Properties.Slug.RichText[0].PlainText
 	}
 
+	for _, tag := range na.Properties.Tags.MultiSelect {
+		article.Tags = append(article.Tags, tag.Name)
+	}
+
+	switch status := na.Properties.Status; {
+	case status.Status != nil:
+		article.Status = articles.ParseStatus(status.Status.Name)
+	case status.Select != nil:
+		article.Status = articles.ParseStatus(status.Select.Name)
+	}
+
+	if na.Properties.PublishAt.Date != nil {
+		// publish at may be a date or a date time
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			if pa, err := time.Parse(layout, na.Properties.PublishAt.Date.Start); err == nil {
+				article.PublishAt = pa
+				break
+			}
+		}
+	}
+
 	return article
 }
 
+// url returns the address of the file whether it is hosted by notion or external
+func (f *file) url() string {
+	if f.Type == "external" {
+		return f.External.Url
+	}
+	return f.File.Url
+}
+
 func (tb textContents) toString() string {
 	var s strings.Builder
 	for _, text := range tb {
-		s.WriteString(text.Text)
+		s.WriteString(text.PlainText)
 	}
 	return s.String()
 }
+
+// toRichText converts notion rich text to the rich text rendered by elements
+func (tb textContents) toRichText() elements.RichText {
+	rt := make(elements.RichText, len(tb))
+	for i, text := range tb {
+		t := elements.Text{
+			Content:       text.PlainText,
+			Bold:          text.Annotations.Bold,
+			Italic:        text.Annotations.Italic,
+			Strikethrough: text.Annotations.Strikethrough,
+			Underline:     text.Annotations.Underline,
+			Code:          text.Annotations.Code,
+			Color:         text.Annotations.Color,
+			Link:          text.Href,
+		}
+
+		switch text.Type {
+		case "equation":
+			t.Equation = true
+			if text.Equation != nil {
+				t.Content = text.Equation.Expression
+			}
+		case "mention":
+			if text.Mention == nil {
+				break
+			}
+			switch text.Mention.Type {
+			case "user":
+				t.Mention = elements.MentionUser
+			case "page":
+				t.Mention = elements.MentionPage
+			case "date":
+				t.Mention = elements.MentionDate
+				if text.Mention.Date != nil {
+					t.DateTime = text.Mention.Date.Start
+				}
+			}
+		}
+
+		rt[i] = t
+	}
+	return rt
+}
@@ -1,42 +1,220 @@
 package notionprovider_test
 
 import (
-	"os"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/so-heil/goblog/business/notionprovider"
-	"github.com/so-heil/goblog/foundation/notion"
+	"github.com/so-heil/goblog/foundation/notion/notiontest"
 )
 
-func TestProvider(t *testing.T) {
-	apiKey := os.Getenv("NOTION_TEST_API_KEY")
-	databaseID := os.Getenv("NOTION_TEST_DATABASE_ID")
+const databaseID = "articles-database"
 
-	if apiKey == "" {
-		t.Fatal("notion test api key should be provided as NOTION_TEST_API_KEY environment variable")
-	}
+func newProvider(t *testing.T) (*notionprovider.Provider, *notiontest.Server) {
+	srv := notiontest.NewServer()
+	t.Cleanup(srv.Close)
 
-	if databaseID == "" {
-		t.Fatal("notion test database id should be provided as NOTION_TEST_DATABASE_ID environment variable")
+	if err := srv.LoadFile("testdata/notion.json"); err != nil {
+		t.Fatalf("load fixture: %s", err)
 	}
+	// small pages make every request in the fixture paginate
+	srv.SetPageSize(2)
 
-	client := notion.NewClient(apiKey)
-	p := notionprovider.NewProvider(client, databaseID)
+	return notionprovider.NewProvider(srv.Client(), databaseID), srv
+}
 
-	articles, err := p.Articles()
+func TestProvider(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newProvider(t)
+
+	articles, err := p.Articles(ctx)
 	if err != nil {
 		t.Fatalf("should fetch pages: %s", err)
 	}
-	if len(articles) == 0 {
-		t.Fatal("should return at least one article")
+	if len(articles) != 3 {
+		t.Fatalf("should return the 3 articles in the database, got: %d", len(articles))
 	}
 
 	sample := articles[0]
-	blocks, err := p.Content(sample.ID)
+	if sample.Title != "First Article" || sample.Slug != "first-article" || sample.Excerpt != "The first article" {
+		t.Errorf("article properties should be read from notion page, got: %+v", sample)
+	}
+	if strings.Join(sample.Tags, ",") != "Go,Testing" {
+		t.Errorf("article tags should be read from notion page, got: %v", sample.Tags)
+	}
+	if sample.WrittenAt.Format("2006-01-02") != "2023-11-20" {
+		t.Errorf("article written at should be read from notion page, got: %s", sample.WrittenAt)
+	}
+
+	about, err := p.AboutPage(ctx)
+	if err != nil {
+		t.Fatalf("should fetch about page: %s", err)
+	}
+	if about.ID != "about" || about.SubTitle != "Software engineer" {
+		t.Errorf("about page should be read from notion page, got: %+v", about)
+	}
+
+	blocks, err := p.Content(ctx, sample.ID)
 	if err != nil {
 		t.Fatalf("should get article content: article id: %s: %s", sample.ID, err)
 	}
-	if len(blocks) == 0 {
-		t.Fatal("sample article should have at least one section block")
+	if len(blocks) != 2 {
+		t.Fatalf("sample article should have 2 divider delimited section blocks, got: %d", len(blocks))
+	}
+	if blocks[0].Title != "Introduction" || blocks[1].Title != "Details" {
+		t.Errorf("sections should be titled by their first heading, got: %q, %q", blocks[0].Title, blocks[1].Title)
+	}
+
+	buf := new(bytes.Buffer)
+	for _, b := range blocks {
+		if err := b.Component.Render(ctx, buf); err != nil {
+			t.Fatalf("render section: %s", err)
+		}
+	}
+	html := buf.String()
+
+	for _, want := range []string{
+		"<strong>bold</strong>",
+		`<a href="https://go.dev">a link</a>`,
+		"Nested item",
+		"Deeply nested",
+		"<details>",
+		">Println</span>",
+		"A quote",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("rendered content should contain %q", want)
+		}
+	}
+	// the synced block duplicates the toggle content
+	if strings.Count(html, "Hidden in a toggle") != 2 {
+		t.Errorf("nested toggle content should be rendered in the toggle and the synced block")
+	}
+}
+
+func TestProviderErrors(t *testing.T) {
+	ctx := context.Background()
+	p, srv := newProvider(t)
+
+	// rate limits are retried by the client
+	srv.Fail(2, http.StatusTooManyRequests, "rate_limited")
+	if _, err := p.Articles(ctx); err != nil {
+		t.Fatalf("should retry rate limited requests: %s", err)
+	}
+
+	srv.Fail(1, http.StatusBadRequest, "validation_error")
+	if _, err := p.Articles(ctx); err == nil {
+		t.Fatal("should fail on notion errors")
+	}
+
+	if _, err := p.Content(ctx, "missing-page"); err == nil {
+		t.Fatal("should fail on missing pages")
+	}
+}
+
+func TestProviderBlockTypes(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newProvider(t)
+
+	blocks, err := p.Content(ctx, "article-2")
+	if err != nil {
+		t.Fatalf("should get article content: %s", err)
+	}
+
+	buf := new(bytes.Buffer)
+	for _, b := range blocks {
+		if err := b.Component.Render(ctx, buf); err != nil {
+			t.Fatalf("render section: %s", err)
+		}
+	}
+	html := buf.String()
+
+	for _, want := range []string{
+		"<ol><li>Step one</li><li>Step two</li></ol>",
+		`<a href="https://go.dev"><strong><i>composed</i></strong></a>`,
+		`<s><span class="text-red-400"> struck</span></s>`,
+		`<math xmlns="http://www.w3.org/1998/Math/MathML" display="inline"><semantics><msup><mi>x</mi><mn>2</mn></msup>`,
+		`<span class="mention opacity-80">@Soheil</span>`,
+		`<time datetime="2023-11-20">2023-11-20</time>`,
+		`<input type="checkbox" checked disabled>`,
+		"💡",
+		"<th>Name</th><th>Value</th>",
+		"<td>answer</td><td>42</td>",
+		`<math xmlns="http://www.w3.org/1998/Math/MathML" display="block">`,
+		`<annotation encoding="application/x-tex">e=mc^2</annotation>`,
+		`href="https://go.dev/blog"`,
+		`src="https://www.youtube.com/embed/abc123"`,
+		`<audio class="w-full" src="https://example.com/talk.mp3"`,
+		">slides.key</a>",
+		`data="https://example.com/paper.pdf"`,
+		"A child page",
+		`data-unsupported-block="ai_block"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("rendered content should contain %q\n%s", want, html)
+		}
+	}
+	if strings.Contains(html, "table_of_contents") {
+		t.Error("table of contents blocks should not be rendered")
+	}
+}
+
+func TestProviderNestedChildren(t *testing.T) {
+	ctx := context.Background()
+	p, srv := newProvider(t)
+
+	// a chain of toggles each nesting the next one, deeper than the children followed
+	toggle := func(level int) notiontest.Object {
+		title := fmt.Sprintf("Level %d", level)
+		return notiontest.Object{
+			"object":       "block",
+			"id":           fmt.Sprintf("level-%d", level),
+			"type":         "toggle",
+			"has_children": true,
+			"toggle": notiontest.Object{
+				"rich_text": []any{notiontest.Object{"type": "text", "plain_text": title, "text": notiontest.Object{"content": title}}},
+			},
+		}
+	}
+	srv.SetChildren("nested-page", toggle(1))
+	for level := 1; level < 8; level++ {
+		srv.SetChildren(fmt.Sprintf("level-%d", level), toggle(level+1))
+	}
+
+	before := srv.Requests()
+	blocks, err := p.Content(ctx, "nested-page")
+	if err != nil {
+		t.Fatalf("should get nested content: %s", err)
+	}
+	// the page children and the children of the first 5 levels
+	if requests := srv.Requests() - before; requests != 6 {
+		t.Errorf("children should be requested down to the depth limit only, requests: %d", requests)
+	}
+
+	buf := new(bytes.Buffer)
+	for _, b := range blocks {
+		if err := b.Component.Render(ctx, buf); err != nil {
+			t.Fatalf("render section: %s", err)
+		}
+	}
+	html := buf.String()
+
+	for level := 1; level <= 6; level++ {
+		if !strings.Contains(html, fmt.Sprintf("Level %d<", level)) {
+			t.Errorf("nested block at level %d should be rendered", level)
+		}
+	}
+	if strings.Contains(html, "Level 7") {
+		t.Error("blocks below the depth limit should not be rendered")
+	}
+	if strings.Count(html, "<details>") != 6 {
+		t.Errorf("every toggle should be rendered inside its parent, got %d", strings.Count(html, "<details>"))
+	}
+	if strings.Index(html, "Level 2") > strings.Index(html, "</details>") {
+		t.Error("children should be rendered inside their parent toggle")
 	}
 }
@@ -0,0 +1,50 @@
+package pages
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+// content encodings pages are pre-compressed with
+const (
+	EncodingBrotli = "br"
+	EncodingGzip   = "gzip"
+)
+
+// Encodings are the content encodings every stored page has a variant for, in order of preference
+var Encodings = []string{EncodingBrotli, EncodingGzip}
+
+// compress returns the variants of content compressed with every encoding in Encodings, pages are compressed once
+// when they're built at the highest level as they're served many times
+func compress(content []byte) (map[string][]byte, error) {
+	encoded := make(map[string][]byte, len(Encodings))
+	for _, encoding := range Encodings {
+		buf := new(bytes.Buffer)
+
+		var w io.WriteCloser
+		switch encoding {
+		case EncodingBrotli:
+			w = brotli.NewWriterLevel(buf, brotli.BestCompression)
+		case EncodingGzip:
+			gw, err := gzip.NewWriterLevel(buf, gzip.BestCompression)
+			if err != nil {
+				return nil, fmt.Errorf("new gzip writer: %w", err)
+			}
+			w = gw
+		}
+
+		if _, err := w.Write(content); err != nil {
+			return nil, fmt.Errorf("%s compress: %w", encoding, err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("%s compress: %w", encoding, err)
+		}
+		encoded[encoding] = buf.Bytes()
+	}
+
+	return encoded, nil
+}
@@ -0,0 +1,376 @@
+package pages
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/so-heil/goblog/business/articles"
+)
+
+const RSSFeedID = "rss_feed"
+const AtomFeedID = "atom_feed"
+const JSONFeedID = "json_feed"
+
+// paths feeds are served under
+const (
+	RSSFeedPath  = "/feed.xml"
+	AtomFeedPath = "/atom.xml"
+	JSONFeedPath = "/feed.json"
+)
+
+// maxFeedItems is the number of latest articles feeds carry
+const maxFeedItems = 20
+
+// feedItem is a feed entry shared by every feed format
+type feedItem struct {
+	article articles.Article
+	link    string
+	// content is the full article HTML, empty when feeds carry excerpts
+	content string
+}
+
+// feed is the content shared by every feed format
+type feed struct {
+	site     Site
+	provider Provider
+	storer   Store
+	// reuseBodies is whether stored article bodies were rendered by the current build and can be reused
+	reuseBodies bool
+}
+
+// newFeedPages creates the RSS 2.0, Atom and JSON Feed feeds of the latest articles of site, they're rebuilt whenever
+// their content changes, e.g. when an article is edited or the site is described differently
+func newFeedPages(ctx context.Context, site Site, atcls []articles.Article, provider Provider, storer Store, reuseBodies bool) ([]Page, error) {
+	f := &feed{site: site, provider: provider, storer: storer, reuseBodies: reuseBodies}
+	items, err := f.feedItems(ctx, atcls)
+	if err != nil {
+		return nil, err
+	}
+
+	formats := []struct {
+		id     string
+		encode func([]feedItem) ([]byte, error)
+	}{
+		{RSSFeedID, f.encodeRSS},
+		{AtomFeedID, f.encodeAtom},
+		{JSONFeedID, f.encodeJSON},
+	}
+	feedPages := make([]Page, len(formats))
+	for i, format := range formats {
+		content, err := format.encode(items)
+		if err != nil {
+			return nil, fmt.Errorf("encode feed %s: %w", format.id, err)
+		}
+		if feedPages[i], err = newPrebuiltPage(ctx, storer, format.id, content); err != nil {
+			return nil, err
+		}
+	}
+
+	return feedPages, nil
+}
+
+// url returns the absolute url of path in site
+func (f *feed) url(path string) string {
+	return strings.TrimRight(f.site.URL, "/") + path
+}
+
+// feedItems returns the latest of atcls as feed items with their content rendered if feeds carry full content
+func (f *feed) feedItems(ctx context.Context, atcls []articles.Article) ([]feedItem, error) {
+	var latest []articles.Article
+	for _, article := range atcls {
+		if article.Slug != "" {
+			latest = append(latest, article)
+		}
+	}
+	sort.SliceStable(latest, func(i, j int) bool {
+		return latest[i].WrittenAt.After(latest[j].WrittenAt)
+	})
+	if len(latest) > maxFeedItems {
+		latest = latest[:maxFeedItems]
+	}
+
+	items := make([]feedItem, len(latest))
+	for i, article := range latest {
+		items[i] = feedItem{article: article, link: f.url(fmt.Sprintf("/blog/%s", article.Slug))}
+		if !f.site.FullContentFeeds {
+			continue
+		}
+
+		content, err := f.content(ctx, article)
+		if err != nil {
+			return nil, fmt.Errorf("article %s feed content: %w", article.Slug, err)
+		}
+		items[i].content = content
+	}
+
+	return items, nil
+}
+
+// relativeURL matches attributes referencing paths on the website, e.g. self-hosted files
+var relativeURL = regexp.MustCompile(`((?:href|src|data)=")/([^/])`)
+
+// content renders the article content as HTML without the page layout, links are made absolute as feed readers
+// show it outside the website
+func (f *feed) content(ctx context.Context, article articles.Article) (string, error) {
+	body, err := f.body(ctx, article)
+	if err != nil {
+		return "", err
+	}
+
+	return relativeURL.ReplaceAllString(string(body), "${1}"+strings.TrimRight(f.site.URL, "/")+"/$2"), nil
+}
+
+// body returns the article body with its files self-hosted, the stored body is reused while the article is unchanged
+// so unchanged articles are not retrieved from the provider and their files are not downloaded again
+func (f *feed) body(ctx context.Context, article articles.Article) ([]byte, error) {
+	if f.reuseBodies {
+		body, version, err := f.storer.LoadBody(ctx, article.Slug)
+		if err != nil && !errors.Is(err, ErrArticleNotFound) {
+			return nil, fmt.Errorf("load stored body: %w", err)
+		}
+		if err == nil && version.Equal(article.LastEditedTime) {
+			return body, nil
+		}
+	}
+
+	sections, err := f.provider.Content(ctx, article.ID)
+	if err != nil {
+		return nil, fmt.Errorf("retrieve article content from provider: %w", err)
+	}
+
+	buf := new(bytes.Buffer)
+	var files []string
+	for _, section := range sections {
+		if err := section.Component.Render(ctx, buf); err != nil {
+			return nil, fmt.Errorf("render section: %w", err)
+		}
+		files = append(files, section.Files...)
+	}
+
+	body, err := hostFiles(ctx, f.storer, buf.Bytes(), files)
+	if err != nil {
+		return nil, fmt.Errorf("host content files: %w", err)
+	}
+	if err := f.storer.StoreBody(ctx, article.Slug, body, article.LastEditedTime); err != nil {
+		return nil, fmt.Errorf("store body: %w", err)
+	}
+
+	return body, nil
+}
+
+// updated is the last time any of the items was edited
+func updated(items []feedItem) time.Time {
+	var t time.Time
+	for _, item := range items {
+		if item.article.LastEditedTime.After(t) {
+			t = item.article.LastEditedTime
+		}
+	}
+	return t
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string      `xml:"title"`
+	Link          string      `xml:"link"`
+	Description   string      `xml:"description"`
+	AtomLink      rssAtomLink `xml:"atom:link"`
+	LastBuildDate string      `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem   `xml:"item"`
+}
+
+type rssAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// encodeRSS encodes items as an RSS 2.0 feed
+func (f *feed) encodeRSS(items []feedItem) ([]byte, error) {
+	channel := rssChannel{
+		Title:       f.site.Title,
+		Link:        f.url("/blog"),
+		Description: f.site.Description,
+		AtomLink:    rssAtomLink{Href: f.url(RSSFeedPath), Rel: "self", Type: "application/rss+xml"},
+		Items:       make([]rssItem, len(items)),
+	}
+	if t := updated(items); !t.IsZero() {
+		channel.LastBuildDate = t.Format(time.RFC1123Z)
+	}
+
+	for i, item := range items {
+		description := item.article.Excerpt
+		if item.content != "" {
+			description = item.content
+		}
+		channel.Items[i] = rssItem{
+			Title:       item.article.Title,
+			Link:        item.link,
+			GUID:        rssGUID{IsPermaLink: true, Value: item.link},
+			PubDate:     item.article.WrittenAt.Format(time.RFC1123Z),
+			Description: description,
+		}
+	}
+
+	return encodeXML(rss{Version: "2.0", AtomNS: "http://www.w3.org/2005/Atom", Channel: channel})
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	ID       string      `xml:"id"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   atomAuthor  `xml:"author"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title     string     `xml:"title"`
+	ID        string     `xml:"id"`
+	Links     []atomLink `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Summary   *atomText  `xml:"summary,omitempty"`
+	Content   *atomText  `xml:"content,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// encodeAtom encodes items as an Atom feed
+func (f *feed) encodeAtom(items []feedItem) ([]byte, error) {
+	af := atomFeed{
+		Title:    f.site.Title,
+		Subtitle: f.site.Description,
+		ID:       f.url("/"),
+		Updated:  updated(items).Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: f.url(AtomFeedPath), Rel: "self", Type: "application/atom+xml"},
+			{Href: f.url("/blog"), Rel: "alternate", Type: "text/html"},
+		},
+		Author:  atomAuthor{Name: f.site.Title},
+		Entries: make([]atomEntry, len(items)),
+	}
+
+	for i, item := range items {
+		entry := atomEntry{
+			Title:     item.article.Title,
+			ID:        item.link,
+			Links:     []atomLink{{Href: item.link, Rel: "alternate", Type: "text/html"}},
+			Published: item.article.WrittenAt.Format(time.RFC3339),
+			Updated:   item.article.LastEditedTime.Format(time.RFC3339),
+		}
+		if item.article.Excerpt != "" {
+			entry.Summary = &atomText{Type: "text", Body: item.article.Excerpt}
+		}
+		if item.content != "" {
+			entry.Content = &atomText{Type: "html", Body: item.content}
+		}
+		af.Entries[i] = entry
+	}
+
+	return encodeXML(af)
+}
+
+func encodeXML(v any) ([]byte, error) {
+	buf := bytes.NewBufferString(xml.Header)
+	enc := xml.NewEncoder(buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string `json:"id"`
+	URL           string `json:"url"`
+	Title         string `json:"title"`
+	Summary       string `json:"summary,omitempty"`
+	ContentHTML   string `json:"content_html,omitempty"`
+	ContentText   string `json:"content_text,omitempty"`
+	DatePublished string `json:"date_published"`
+	DateModified  string `json:"date_modified"`
+}
+
+// encodeJSON encodes items as a JSON Feed 1.1
+func (f *feed) encodeJSON(items []feedItem) ([]byte, error) {
+	jf := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.site.Title,
+		HomePageURL: f.url("/"),
+		FeedURL:     f.url(JSONFeedPath),
+		Description: f.site.Description,
+		Items:       make([]jsonFeedItem, len(items)),
+	}
+
+	for i, item := range items {
+		jItem := jsonFeedItem{
+			ID:            item.link,
+			URL:           item.link,
+			Title:         item.article.Title,
+			Summary:       item.article.Excerpt,
+			DatePublished: item.article.WrittenAt.Format(time.RFC3339),
+			DateModified:  item.article.LastEditedTime.Format(time.RFC3339),
+		}
+		// an item should have either html or text content
+		if item.content != "" {
+			jItem.ContentHTML = item.content
+		} else {
+			jItem.ContentText = item.article.Excerpt
+		}
+		jf.Items[i] = jItem
+	}
+
+	return json.MarshalIndent(jf, "", "  ")
+}
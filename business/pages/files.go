@@ -0,0 +1,124 @@
+package pages
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// FilesPath is the path self-hosted files are served under
+const FilesPath = "/files/"
+
+// maxFileSize is the largest remote file that is self-hosted
+const maxFileSize = 100 << 20
+
+// filesPage is a Page referencing remote files that should be self-hosted, known once the page is rendered
+type filesPage interface {
+	Files() []string
+}
+
+// hostFiles downloads every file in urls, stores it in storer keyed by its content hash and rewrites
+// the references to it in content to its stable path under FilesPath
+func hostFiles(ctx context.Context, storer Store, content []byte, urls []string) ([]byte, error) {
+	page := string(content)
+	for _, u := range urls {
+		file, err := download(ctx, u)
+		if err != nil {
+			return nil, err
+		}
+
+		name := fileName(u, file)
+		if err := storer.StoreFile(ctx, name, file); err != nil {
+			return nil, fmt.Errorf("store file %s: %w", name, err)
+		}
+
+		// urls are escaped in attributes so both forms are replaced
+		local := FilesPath + name
+		page = strings.ReplaceAll(page, html.EscapeString(u), local)
+		page = strings.ReplaceAll(page, u, local)
+	}
+
+	return []byte(page), nil
+}
+
+// deleteUnreferencedFiles deletes the stored files that no stored page references anymore, e.g. the previous version
+// of an edited image, and returns the number of deleted files
+func deleteUnreferencedFiles(ctx context.Context, storer Store) (int, error) {
+	names, err := storer.Files()
+	if err != nil {
+		return 0, err
+	}
+	if len(names) == 0 {
+		return 0, nil
+	}
+
+	unreferenced := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		unreferenced[name] = struct{}{}
+	}
+	for id := range storer.Versions() {
+		content, err := storer.Load(ctx, id)
+		if err != nil {
+			return 0, fmt.Errorf("load page %s: %w", id, err)
+		}
+		for name := range unreferenced {
+			if bytes.Contains(content, []byte(FilesPath+name)) {
+				delete(unreferenced, name)
+			}
+		}
+	}
+
+	for name := range unreferenced {
+		if err := storer.DeleteFile(ctx, name); err != nil {
+			return 0, fmt.Errorf("delete file %s: %w", name, err)
+		}
+	}
+	return len(unreferenced), nil
+}
+
+// download fetches the file at u
+func download(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("construct file request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download file: %s: status code %d", req.URL.Redacted(), resp.StatusCode)
+	}
+
+	file, err := io.ReadAll(io.LimitReader(resp.Body, maxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read file body: %w", err)
+	}
+	if len(file) > maxFileSize {
+		return nil, fmt.Errorf("download file: %s: larger than %d bytes", req.URL.Redacted(), maxFileSize)
+	}
+
+	return file, nil
+}
+
+// fileName is the name a file is stored under, the hash of its content keeping the extension of the url it was fetched from
+func fileName(u string, content []byte) string {
+	sum := sha256.Sum256(content)
+	name := hex.EncodeToString(sum[:16])
+
+	if parsed, err := url.Parse(u); err == nil {
+		name += strings.ToLower(path.Ext(parsed.Path))
+	}
+	return name
+}
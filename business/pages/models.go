@@ -1,19 +1,27 @@
 package pages
 
 import (
+	"context"
 	"errors"
 	"time"
 
 	"github.com/a-h/templ"
 	"github.com/so-heil/goblog/business/articles"
+	"github.com/so-heil/goblog/business/templates/components/toc"
 )
 
 var ErrArticleNotFound = errors.New("article not found")
 
+var ErrFileNotFound = errors.New("file not found")
+
 // SectionBlock is a section containing content in form of templ.Component
 type SectionBlock struct {
 	Title     string
 	Component templ.Component
+	// Files are urls of remote files referenced by Component that expire, they are self-hosted when the page is built
+	Files []string
+	// Headings are the h1 to h3 headings of the section in order, their anchors are unique in the page
+	Headings []toc.Heading
 }
 
 // About contains a about page data that provider should return
@@ -25,26 +33,64 @@ type About struct {
 	LastEditedTime time.Time
 }
 
-// Provider is any type that can provide the website content
+// Site is the website wide configuration pages are built with
+type Site struct {
+	// URL is the absolute base URL the website is served from e.g. https://example.com, feeds need absolute links
+	URL string
+	// Title and Description describe the website in feeds
+	Title       string
+	Description string
+	// FullContentFeeds makes feed items carry the full article content instead of its excerpt
+	FullContentFeeds bool
+	// IncludeDrafts publishes every article regardless of its status and publish time, for local preview
+	IncludeDrafts bool
+	// Robots is the robots.txt content, empty allows every crawler, the sitemap is always referenced
+	Robots string
+	// Build identifies the code, templates and assets pages are rendered with, every stored page is rebuilt
+	// when it differs from the build they were rendered with
+	Build string
+}
+
+// Provider is any type that can provide the website content, calls should give up when ctx is done
 type Provider interface {
 	// Articles should return all articles accessed by the provider
-	Articles() ([]articles.Article, error)
+	Articles(ctx context.Context) ([]articles.Article, error)
 	// Content shpuld return the corresponding page content as SectionBlocks
-	Content(id string) ([]SectionBlock, error)
+	Content(ctx context.Context, id string) ([]SectionBlock, error)
 	// AboutPage returns the about page data as AboutData
-	AboutPage() (About, error)
+	AboutPage(ctx context.Context) (About, error)
 }
 
 // Store is any type that can store and retrieve website pages
 type Store interface {
-	// Store can store an Article content and track it's version for later use
-	Store(id string, content []byte, version time.Time) error
+	// Store can store an Article content with its pre-compressed variants by content encoding and track it's version for later use
+	Store(ctx context.Context, id string, content []byte, encoded map[string][]byte, version time.Time) error
 	// Load should load the requested article content
-	Load(id string) ([]byte, error)
+	Load(ctx context.Context, id string) ([]byte, error)
+	// LoadEncoded should load the variant of the requested article content compressed with encoding
+	LoadEncoded(ctx context.Context, id string, encoding string) ([]byte, error)
 	// Delete shpuld delete an article and its version from Store
-	Delete(id string) error
+	Delete(ctx context.Context, id string) error
 	// Versions should return a map of all present articles in Store with their corresponding version
 	Versions() map[string]time.Time
+	// Version should return the version of the page with id and whether it is present in Store
+	Version(id string) (time.Time, bool)
+	// Build should return the build stored pages were rendered with, empty if none is stored
+	Build(ctx context.Context) (string, error)
+	// StoreBuild stores the build stored pages were rendered with
+	StoreBuild(ctx context.Context, build string) error
+	// StoreBody stores the rendered body of the article page with id at version, feeds with full content reuse it
+	StoreBody(ctx context.Context, id string, body []byte, version time.Time) error
+	// LoadBody should load the stored body of the article page with id and the version it was rendered at
+	LoadBody(ctx context.Context, id string) ([]byte, time.Time, error)
+	// StoreFile stores a file referenced by pages under name
+	StoreFile(ctx context.Context, name string, content []byte) error
+	// LoadFile should load the file stored under name
+	LoadFile(ctx context.Context, name string) ([]byte, error)
+	// DeleteFile deletes the file stored under name
+	DeleteFile(ctx context.Context, name string) error
+	// Files should return the names of all files present in Store
+	Files() ([]string, error)
 }
 
 // Page represents a website page
@@ -52,7 +98,7 @@ type Page interface {
 	// IsUpdated reports that if page has been updated since the last update
 	IsUpdated() bool
 	// Render return the page HTML content as a templ.Component
-	Render() (templ.Component, error)
+	Render(ctx context.Context) (templ.Component, error)
 	// ID returns the page's unique identifier used in Store
 	ID() string
 	// Version returns the current version (update) of the page
@@ -3,20 +3,25 @@ package pages
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/a-h/templ"
 	"github.com/so-heil/goblog/business/articles"
 	"github.com/so-heil/goblog/business/templates/components/breadcrumb"
+	"github.com/so-heil/goblog/business/templates/components/toc"
 	"github.com/so-heil/goblog/business/templates/pages/about"
 	"github.com/so-heil/goblog/business/templates/pages/blog"
 	"github.com/so-heil/goblog/business/templates/pages/notfound"
+	"github.com/so-heil/goblog/business/templates/pages/servererror"
 )
 
 const BlogPageID = "blog_page"
 const AboutPageID = "about_page"
 const NotFoundPageID = "404_page"
+const ServerErrorPageID = "500_page"
 
 type BlogPage struct {
 	articles          []articles.Article
@@ -35,7 +40,7 @@ func (bp *BlogPage) IsUpdated() bool {
 	return bp.anyArticleUpdated
 }
 
-func (bp *BlogPage) Render() (templ.Component, error) {
+func (bp *BlogPage) Render(ctx context.Context) (templ.Component, error) {
 	blogArticles := make([]blog.Article, len(bp.articles))
 	for i, article := range bp.articles {
 		blogArticles[i] = toBlogArticle(article)
@@ -53,6 +58,7 @@ type ArticlePage struct {
 	article  articles.Article
 	provider Provider
 	versions map[string]time.Time
+	files    []string
 }
 
 func (ap *ArticlePage) ID() string {
@@ -65,27 +71,32 @@ func (ap *ArticlePage) Version() time.Time {
 
 func (ap *ArticlePage) IsUpdated() bool {
 	aboutUpdate, ok := ap.versions[ap.article.Slug]
-	return !ok || aboutUpdate != ap.article.LastEditedTime
+	return !ok || !aboutUpdate.Equal(ap.article.LastEditedTime)
 }
 
-func (ap *ArticlePage) Render() (templ.Component, error) {
-	sections, err := ap.provider.Content(ap.article.ID)
+func (ap *ArticlePage) Files() []string {
+	return ap.files
+}
+
+func (ap *ArticlePage) Render(ctx context.Context) (templ.Component, error) {
+	sections, err := ap.provider.Content(ctx, ap.article.ID)
 	if err != nil {
 		return nil, fmt.Errorf("retrieve article content from provider: %w", err)
 	}
 
 	components := make([]templ.Component, len(sections))
-	headings := make([]string, len(sections))
+	var headings []toc.Heading
 
 	for i := 0; i < len(sections); i++ {
 		components[i] = sections[i].Component
-		headings[i] = sections[i].Title
+		headings = append(headings, sections[i].Headings...)
+		ap.files = append(ap.files, sections[i].Files...)
 	}
 
 	page := blog.ArticlePage([]breadcrumb.Link{{
 		Title: ap.article.Title,
 		Href:  fmt.Sprintf("/blog/%s", ap.article.Slug),
-	}}, toBlogArticle(ap.article), components, headings)
+	}}, toBlogArticle(ap.article), components, toc.Nest(headings))
 
 	return page, nil
 }
@@ -94,6 +105,7 @@ type AboutPage struct {
 	data     *About
 	provider Provider
 	versions map[string]time.Time
+	files    []string
 }
 
 func (ap *AboutPage) ID() string {
@@ -106,11 +118,15 @@ func (ap *AboutPage) Version() time.Time {
 
 func (ap *AboutPage) IsUpdated() bool {
 	aboutUpdate, ok := ap.versions[AboutPageID]
-	return !ok || aboutUpdate != ap.data.LastEditedTime
+	return !ok || !aboutUpdate.Equal(ap.data.LastEditedTime)
 }
 
-func (ap *AboutPage) Render() (templ.Component, error) {
-	sections, err := ap.provider.Content(ap.data.ID)
+func (ap *AboutPage) Files() []string {
+	return ap.files
+}
+
+func (ap *AboutPage) Render(ctx context.Context) (templ.Component, error) {
+	sections, err := ap.provider.Content(ctx, ap.data.ID)
 	if err != nil {
 		return nil, fmt.Errorf("retrieve about content from provider: %w", err)
 	}
@@ -118,6 +134,7 @@ func (ap *AboutPage) Render() (templ.Component, error) {
 	componenets := make([]templ.Component, len(sections))
 	for i := 0; i < len(sections); i++ {
 		componenets[i] = sections[i].Component
+		ap.files = append(ap.files, sections[i].Files...)
 	}
 
 	page := about.AboutPage([]breadcrumb.Link{{
@@ -141,7 +158,7 @@ func (n *NotFoundPage) IsUpdated() bool {
 	return !ok
 }
 
-func (n *NotFoundPage) Render() (templ.Component, error) {
+func (n *NotFoundPage) Render(ctx context.Context) (templ.Component, error) {
 	return notfound.NotFoundPage(), nil
 }
 
@@ -153,18 +170,80 @@ func (n *NotFoundPage) Version() time.Time {
 	return time.Now()
 }
 
-// build renders the page and returns the rendered content that can be stored
-func build(page Page) ([]byte, error) {
-	component, err := page.Render()
+type ServerErrorPage struct {
+	versions map[string]time.Time
+}
+
+func (s *ServerErrorPage) IsUpdated() bool {
+	_, ok := s.versions[ServerErrorPageID]
+	return !ok
+}
+
+func (s *ServerErrorPage) Render(ctx context.Context) (templ.Component, error) {
+	return servererror.ServerErrorPage(), nil
+}
+
+func (s *ServerErrorPage) ID() string {
+	return ServerErrorPageID
+}
+
+func (s *ServerErrorPage) Version() time.Time {
+	return time.Now()
+}
+
+// PrebuiltPage is a page rendered upfront, it's updated only when its content differs from the stored one,
+// used for pages without a version of their own e.g. tag pages whose content changes with their articles
+type PrebuiltPage struct {
+	id      string
+	content []byte
+	stored  []byte
+}
+
+// newPrebuiltPage creates the page with id and content loading its stored content from storer
+func newPrebuiltPage(ctx context.Context, storer Store, id string, content []byte) (*PrebuiltPage, error) {
+	stored, err := storer.Load(ctx, id)
+	if err != nil && !errors.Is(err, ErrArticleNotFound) {
+		return nil, fmt.Errorf("load stored page %s: %w", id, err)
+	}
+
+	return &PrebuiltPage{id: id, content: content, stored: stored}, nil
+}
+
+func (pp *PrebuiltPage) ID() string {
+	return pp.id
+}
+
+func (pp *PrebuiltPage) Version() time.Time {
+	return time.Now()
+}
+
+func (pp *PrebuiltPage) IsUpdated() bool {
+	return !bytes.Equal(pp.content, pp.stored)
+}
+
+func (pp *PrebuiltPage) Render(ctx context.Context) (templ.Component, error) {
+	return rawComponent(pp.content), nil
+}
+
+// build renders the page and returns the rendered content that can be stored,
+// remote files the page references are self-hosted in storer
+func build(ctx context.Context, page Page, storer Store) ([]byte, error) {
+	component, err := page.Render(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("render page: %w", err)
 	}
 
-	content, err := pageContent(component)
+	content, err := pageContent(ctx, component)
 	if err != nil {
 		return nil, fmt.Errorf("page content: %w", err)
 	}
 
+	if fp, ok := page.(filesPage); ok {
+		if content, err = hostFiles(ctx, storer, content, fp.Files()); err != nil {
+			return nil, fmt.Errorf("host page files: %w", err)
+		}
+	}
+
 	return content, nil
 }
 
@@ -174,15 +253,34 @@ func toBlogArticle(a articles.Article) blog.Article {
 		Excerpt:   a.Excerpt,
 		WrittenAt: a.WrittenAt,
 		Slug:      a.Slug,
+		Tags:      toBlogTags(a.Tags),
+	}
+}
+
+func toBlogTags(tags []string) []blog.Tag {
+	blogTags := make([]blog.Tag, 0, len(tags))
+	for _, tag := range tags {
+		if TagSlug(tag) != "" {
+			blogTags = append(blogTags, blog.Tag{Name: tag, Href: tagPath(tag)})
+		}
 	}
+	return blogTags
 }
 
 // pageContent renders the component into a buffer and returns the result
-func pageContent(page templ.Component) ([]byte, error) {
+func pageContent(ctx context.Context, page templ.Component) ([]byte, error) {
 	buf := new(bytes.Buffer)
-	if err := page.Render(context.Background(), buf); err != nil {
+	if err := page.Render(ctx, buf); err != nil {
 		return nil, fmt.Errorf("render page: %w", err)
 	}
 
 	return buf.Bytes(), nil
 }
+
+// rawComponent is a component writing content as is, used for pages that are not HTML
+func rawComponent(content []byte) templ.Component {
+	return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		_, err := w.Write(content)
+		return err
+	})
+}
@@ -1,26 +1,40 @@
 package pages_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/andybalholm/brotli"
 	"github.com/dgraph-io/badger/v4"
 	"github.com/so-heil/goblog/business/notionprovider"
 	"github.com/so-heil/goblog/business/pages"
 	"github.com/so-heil/goblog/business/repository"
-	"github.com/so-heil/goblog/foundation/notion"
+	"github.com/so-heil/goblog/foundation/notion/notiontest"
 )
 
 const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 
+// fixture is the notion content pages are built from, shared with the notionprovider tests
+const fixture = "../notionprovider/testdata/notion.json"
+
+var site = pages.Site{URL: "https://example.com/", Title: "Example", Description: "Example blog"}
+
 func randomSlug(n int) string {
 	b := make([]byte, n)
 	for i := range b {
@@ -29,44 +43,57 @@ func randomSlug(n int) string {
 	return string(b)
 }
 
-func TestUpdateStore(t *testing.T) {
-	// setup provider
-	apiKey := os.Getenv("NOTION_TEST_API_KEY")
-	databaseID := os.Getenv("NOTION_TEST_DATABASE_ID")
-	if apiKey == "" {
-		t.Fatal("notion test api key should be provided as NOTION_TEST_API_KEY environment variable")
-	}
-	if databaseID == "" {
-		t.Fatal("notion test database id should be provided as NOTION_TEST_DATABASE_ID environment variable")
-	}
-	client := notion.NewClient(apiKey)
-	p := notionprovider.NewProvider(client, databaseID)
-
-	// setup storer
+// newRepository returns a repository backed by a badger db in a temp dir removed when the test ends
+func newRepository(t *testing.T) *repository.Repository {
 	dir, err := os.MkdirTemp("", "badger-test")
 	if err != nil {
 		t.Fatalf("mkdir temp: %s", err)
 	}
-	defer func(path string) {
-		err := os.RemoveAll(path)
-		if err != nil {
+	t.Cleanup(func() {
+		if err := os.RemoveAll(dir); err != nil {
 			t.Fatalf("remove temp dir: %s", err)
 		}
-	}(dir)
+	})
 
 	db, err := badger.Open(badger.DefaultOptions(dir))
 	if err != nil {
 		t.Fatalf("open badger db with temp dir: %s", err)
 	}
-	defer func(db *badger.DB) {
-		err := db.Close()
-		if err != nil {
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
 			t.Fatalf("close db: %s", err)
 		}
-	}(db)
-	s := repository.New(db)
+	})
+
+	s, err := repository.New(db)
+	if err != nil {
+		t.Fatalf("new repository: %s", err)
+	}
+	return s
+}
+
+// newProvider returns a provider of the notion fixture served by a fake notion server closed when the test ends
+func newProvider(t *testing.T) (*notionprovider.Provider, *notiontest.Server) {
+	srv := notiontest.NewServer()
+	t.Cleanup(srv.Close)
+
+	if err := srv.LoadFile(fixture); err != nil {
+		t.Fatalf("load fixture: %s", err)
+	}
+
+	return notionprovider.NewProvider(srv.Client(), "articles-database"), srv
+}
+
+func TestUpdateStore(t *testing.T) {
+	ctx := context.Background()
+
+	// setup provider
+	p, srv := newProvider(t)
+	client := srv.Client()
 
-	atcls, err := p.Articles()
+	s := newRepository(t)
+
+	atcls, err := p.Articles(ctx)
 	if err != nil {
 		t.Fatalf("should get articles from provider: %s", err)
 	}
@@ -77,7 +104,7 @@ func TestUpdateStore(t *testing.T) {
 
 	sample := atcls[0]
 
-	if err := pages.UpdateStore(p, s, runtime.NumCPU()); err != nil {
+	if err := pages.UpdateStore(ctx, p, s, site, runtime.NumCPU()); err != nil {
 		t.Fatalf("initial seed: %s", err)
 	}
 
@@ -87,7 +114,7 @@ func TestUpdateStore(t *testing.T) {
 
 	}
 
-	sampleContent, err := s.Load(sample.Slug)
+	sampleContent, err := s.Load(ctx, sample.Slug)
 	if err != nil {
 		t.Fatalf("should load sample article content: %s", err)
 	}
@@ -96,8 +123,13 @@ func TestUpdateStore(t *testing.T) {
 		t.Fatal("sample article content should not be empty")
 	}
 
-	time.Sleep(time.Second)
-	if err := pages.UpdateStore(p, s, runtime.NumCPU()); err != nil {
+	for _, id := range []string{pages.NotFoundPageID, pages.ServerErrorPageID} {
+		if _, err := s.Load(ctx, id); err != nil {
+			t.Fatalf("should store error page %s: %s", id, err)
+		}
+	}
+
+	if err := pages.UpdateStore(ctx, p, s, site, runtime.NumCPU()); err != nil {
 		t.Fatalf("initial seed: %s", err)
 	}
 
@@ -132,12 +164,11 @@ func TestUpdateStore(t *testing.T) {
 	}
 }`, newSlug, newSlug)
 
-	if err := client.Request(http.MethodPatch, fmt.Sprintf("/pages/%s", sample.ID), strings.NewReader(updateBody), &struct{}{}); err != nil {
+	if err := client.Request(ctx, http.MethodPatch, fmt.Sprintf("/pages/%s", sample.ID), strings.NewReader(updateBody), &struct{}{}); err != nil {
 		t.Fatalf("request update failed: %s", err)
 	}
-	time.Sleep(time.Second)
 
-	if err := pages.UpdateStore(p, s, runtime.NumCPU()); err != nil {
+	if err := pages.UpdateStore(ctx, p, s, site, runtime.NumCPU()); err != nil {
 		t.Fatalf("initial seed: %s", err)
 	}
 
@@ -145,11 +176,11 @@ func TestUpdateStore(t *testing.T) {
 		t.Fatal("versions should not remain the same after update")
 	}
 
-	if _, err := s.Load(newSlug); err != nil {
+	if _, err := s.Load(ctx, newSlug); err != nil {
 		t.Fatal("newSlug article should be in storer")
 	}
 
-	if _, err := s.Load(sample.Slug); err != nil {
+	if _, err := s.Load(ctx, sample.Slug); err != nil {
 		if !errors.Is(err, pages.ErrArticleNotFound) {
 			t.Fatal("old slug should not be in storer")
 		}
@@ -159,3 +190,744 @@ func TestUpdateStore(t *testing.T) {
 		t.Fatalf("articles cound should remained the same after update")
 	}
 }
+
+// contentProvider replaces the article content of a provider by content
+type contentProvider struct {
+	pages.Provider
+	content func(ctx context.Context, id string) ([]pages.SectionBlock, error)
+}
+
+func (cp contentProvider) Content(ctx context.Context, id string) ([]pages.SectionBlock, error) {
+	return cp.content(ctx, id)
+}
+
+func TestUpdateStoreErrors(t *testing.T) {
+	p, _ := newProvider(t)
+
+	errContent := errors.New("content unavailable")
+	failing := contentProvider{Provider: p, content: func(ctx context.Context, id string) ([]pages.SectionBlock, error) {
+		return nil, errContent
+	}}
+	if err := pages.UpdateStore(context.Background(), failing, newRepository(t), site, runtime.NumCPU()); !errors.Is(err, errContent) {
+		t.Errorf("should return the errors of failing pages, got: %v", err)
+	}
+
+	// the update is canceled while pages are being built e.g. on shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	canceling := contentProvider{Provider: p, content: func(ctx context.Context, id string) ([]pages.SectionBlock, error) {
+		cancel()
+		return p.Content(ctx, id)
+	}}
+	if err := pages.UpdateStore(ctx, canceling, newRepository(t), site, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("should return the cancellation error of abandoned pages, got: %v", err)
+	}
+}
+
+func TestBuildChange(t *testing.T) {
+	ctx := context.Background()
+
+	p, _ := newProvider(t)
+	s := newRepository(t)
+
+	v1 := site
+	v1.Build = "v1"
+	if err := pages.UpdateStore(ctx, p, s, v1, runtime.NumCPU()); err != nil {
+		t.Fatalf("initial seed: %s", err)
+	}
+	if build, err := s.Build(ctx); err != nil || build != "v1" {
+		t.Fatalf("build pages were rendered with should be stored, got: %q, %v", build, err)
+	}
+
+	// pages rendered by an older build, their content did not change since
+	stale := []byte("stale markup")
+	for _, id := range []string{"first-article", pages.AboutPageID, pages.NotFoundPageID, pages.BlogPageID} {
+		version, _ := s.Version(id)
+		if err := s.Store(ctx, id, stale, nil, version); err != nil {
+			t.Fatalf("store stale page: %s", err)
+		}
+	}
+	isStale := func(id string) bool {
+		content, err := s.Load(ctx, id)
+		if err != nil {
+			t.Fatalf("load page %s: %s", id, err)
+		}
+		return bytes.Equal(content, stale)
+	}
+
+	if err := pages.UpdateStore(ctx, p, s, v1, runtime.NumCPU()); err != nil {
+		t.Fatalf("update: %s", err)
+	}
+	if !isStale("first-article") {
+		t.Error("unchanged pages should not be rebuilt by the same build")
+	}
+
+	// a failed update keeps the old build so the next update rebuilds every page again
+	v2 := site
+	v2.Build = "v2"
+	failing := contentProvider{Provider: p, content: func(ctx context.Context, id string) ([]pages.SectionBlock, error) {
+		return nil, errors.New("content unavailable")
+	}}
+	if err := pages.UpdateStore(ctx, failing, s, v2, runtime.NumCPU()); err == nil {
+		t.Fatal("update with failing provider should fail")
+	}
+	if build, _ := s.Build(ctx); build != "v1" {
+		t.Errorf("build should not be stored by failed updates, got: %q", build)
+	}
+
+	if err := pages.UpdateStore(ctx, p, s, v2, runtime.NumCPU()); err != nil {
+		t.Fatalf("update with new build: %s", err)
+	}
+	for _, id := range []string{"first-article", pages.AboutPageID, pages.NotFoundPageID, pages.BlogPageID} {
+		if isStale(id) {
+			t.Errorf("page %s should be rebuilt by a new build", id)
+		}
+	}
+	if build, _ := s.Build(ctx); build != "v2" {
+		t.Errorf("new build should be stored, got: %q", build)
+	}
+}
+
+func TestHostedFiles(t *testing.T) {
+	ctx := context.Background()
+
+	image := []byte("\x89PNG\r\n\x1a\nnot really a png")
+	images := map[string][]byte{
+		"/secure/gopher.PNG": image,
+		"/secure/edited.PNG": []byte("\x89PNG\r\n\x1a\nan edited png"),
+	}
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := images[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	defer files.Close()
+	// notion hosted urls are signed and expire, the query changes on every request
+	imageURL := files.URL + "/secure/gopher.PNG?X-Amz-Signature=abc&X-Amz-Expires=3600"
+
+	p, srv := newProvider(t)
+	setImage := func(u string) {
+		srv.SetChildren("article-1", notiontest.Object{
+			"object":       "block",
+			"id":           "hosted-image",
+			"type":         "image",
+			"has_children": false,
+			"image": notiontest.Object{
+				"type":    "file",
+				"caption": []any{},
+				"file":    notiontest.Object{"url": u, "expiry_time": "2023-11-21T11:00:00.000Z"},
+			},
+		})
+	}
+	setImage(imageURL)
+	client := srv.Client()
+
+	s := newRepository(t)
+
+	if err := pages.UpdateStore(ctx, p, s, site, runtime.NumCPU()); err != nil {
+		t.Fatalf("initial seed: %s", err)
+	}
+
+	stored, err := s.Files()
+	if err != nil {
+		t.Fatalf("list files: %s", err)
+	}
+	if len(stored) != 1 {
+		t.Fatalf("should store the 1 notion hosted file, has: %d", len(stored))
+	}
+	name := stored[0]
+	if !strings.HasSuffix(name, ".png") {
+		t.Errorf("stored file should keep the url extension, got: %s", name)
+	}
+
+	content, err := s.LoadFile(ctx, name)
+	if err != nil {
+		t.Fatalf("load stored file: %s", err)
+	}
+	if !bytes.Equal(content, image) {
+		t.Error("stored file should be the downloaded file")
+	}
+
+	page, err := s.Load(ctx, "first-article")
+	if err != nil {
+		t.Fatalf("load article: %s", err)
+	}
+	if !strings.Contains(string(page), pages.FilesPath+name) {
+		t.Errorf("article should reference the self-hosted file %s", pages.FilesPath+name)
+	}
+	if strings.Contains(string(page), files.URL) {
+		t.Error("article should not reference the notion hosted url")
+	}
+
+	// the image is replaced in notion, the previous file is no longer referenced by any page
+	setImage(files.URL + "/secure/edited.PNG?X-Amz-Signature=def")
+	if err := client.Request(ctx, http.MethodPatch, "/pages/article-1", strings.NewReader(`{}`), &struct{}{}); err != nil {
+		t.Fatalf("request update failed: %s", err)
+	}
+	if err := pages.UpdateStore(ctx, p, s, site, runtime.NumCPU()); err != nil {
+		t.Fatalf("update: %s", err)
+	}
+
+	stored, err = s.Files()
+	if err != nil {
+		t.Fatalf("list files: %s", err)
+	}
+	if len(stored) != 1 || stored[0] == name {
+		t.Errorf("only the file of the edited image should be stored, got: %v", stored)
+	}
+	if _, err := s.LoadFile(ctx, name); !errors.Is(err, pages.ErrFileNotFound) {
+		t.Errorf("unreferenced file should be deleted, got: %v", err)
+	}
+}
+
+func TestArticleHeadings(t *testing.T) {
+	ctx := context.Background()
+
+	heading := func(id, level, title string) notiontest.Object {
+		return notiontest.Object{
+			"object":       "block",
+			"id":           id,
+			"type":         level,
+			"has_children": false,
+			level: notiontest.Object{
+				"rich_text": []any{notiontest.Object{"type": "text", "plain_text": title, "text": notiontest.Object{"content": title}}},
+			},
+		}
+	}
+
+	p, srv := newProvider(t)
+	srv.SetChildren("article-1",
+		heading("h-1", "heading_1", "Getting Started"),
+		heading("h-2", "heading_2", "Setup"),
+		heading("h-3", "heading_3", "Install"),
+		heading("h-4", "heading_2", "Usage"),
+		heading("h-5", "heading_3", "Setup"),
+		heading("h-6", "heading_1", "Setup?!"),
+	)
+
+	s := newRepository(t)
+	if err := pages.UpdateStore(ctx, p, s, site, runtime.NumCPU()); err != nil {
+		t.Fatalf("initial seed: %s", err)
+	}
+
+	content, err := s.Load(ctx, "first-article")
+	if err != nil {
+		t.Fatalf("load article: %s", err)
+	}
+	page := string(content)
+
+	for _, id := range []string{"getting-started", "setup", "install", "usage", "setup-1", "setup-2"} {
+		if strings.Count(page, fmt.Sprintf(`id="%s"`, id)) != 1 {
+			t.Errorf("page should have a single heading with anchor %s", id)
+		}
+		if !strings.Contains(page, fmt.Sprintf(`href="#%s" aria-label="Permalink"`, id)) {
+			t.Errorf("heading %s should have a permalink", id)
+		}
+	}
+
+	// headings are nested under the closest preceding higher level heading
+	nested := `<a class="block md:pl-2" href="#getting-started">Getting Started</a> <div class="pl-4"><ul><li class="font-light"><a class="block md:pl-2" href="#setup">Setup</a> <div class="pl-4"><ul><li class="font-light"><a class="block md:pl-2" href="#install">Install</a> </li></ul></div></li><li class="font-light"><a class="block md:pl-2" href="#usage">Usage</a>`
+	if !strings.Contains(page, nested) {
+		t.Errorf("table of contents should nest headings by level\n%s", page)
+	}
+}
+
+func TestCompressedPages(t *testing.T) {
+	ctx := context.Background()
+
+	p, _ := newProvider(t)
+	s := newRepository(t)
+
+	if err := pages.UpdateStore(ctx, p, s, site, runtime.NumCPU()); err != nil {
+		t.Fatalf("initial seed: %s", err)
+	}
+
+	decoders := map[string]func([]byte) ([]byte, error){
+		pages.EncodingGzip: func(b []byte) ([]byte, error) {
+			r, err := gzip.NewReader(bytes.NewReader(b))
+			if err != nil {
+				return nil, err
+			}
+			return io.ReadAll(r)
+		},
+		pages.EncodingBrotli: func(b []byte) ([]byte, error) {
+			return io.ReadAll(brotli.NewReader(bytes.NewReader(b)))
+		},
+	}
+
+	for id := range s.Versions() {
+		content, err := s.Load(ctx, id)
+		if err != nil {
+			t.Fatalf("load page %s: %s", id, err)
+		}
+
+		for _, encoding := range pages.Encodings {
+			encoded, err := s.LoadEncoded(ctx, id, encoding)
+			if err != nil {
+				t.Fatalf("load %s variant of %s: %s", encoding, id, err)
+			}
+
+			decoded, err := decoders[encoding](encoded)
+			if err != nil {
+				t.Fatalf("decode %s variant of %s: %s", encoding, id, err)
+			}
+			if !bytes.Equal(decoded, content) {
+				t.Errorf("%s variant of %s should decode to the page", encoding, id)
+			}
+		}
+	}
+}
+
+func TestFeeds(t *testing.T) {
+	ctx := context.Background()
+
+	p, srv := newProvider(t)
+	s := newRepository(t)
+
+	fullContent := site
+	fullContent.FullContentFeeds = true
+	if err := pages.UpdateStore(ctx, p, s, fullContent, runtime.NumCPU()); err != nil {
+		t.Fatalf("initial seed: %s", err)
+	}
+
+	rssContent, err := s.Load(ctx, pages.RSSFeedID)
+	if err != nil {
+		t.Fatalf("load rss feed: %s", err)
+	}
+	var rss struct {
+		Channel struct {
+			Title string `xml:"title"`
+			Items []struct {
+				Link        string `xml:"link"`
+				Description string `xml:"description"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(rssContent, &rss); err != nil {
+		t.Fatalf("rss feed should be valid xml: %s", err)
+	}
+	if rss.Channel.Title != site.Title || len(rss.Channel.Items) == 0 {
+		t.Fatalf("rss feed should describe site articles, got: %+v", rss.Channel)
+	}
+	for _, item := range rss.Channel.Items {
+		if !strings.HasPrefix(item.Link, "https://example.com/blog/") {
+			t.Errorf("feed item links should be absolute article urls, got: %s", item.Link)
+		}
+	}
+	if !strings.Contains(string(rssContent), "&lt;strong&gt;bold&lt;/strong&gt;") {
+		t.Errorf("full content feed should contain the articles html")
+	}
+
+	atomContent, err := s.Load(ctx, pages.AtomFeedID)
+	if err != nil {
+		t.Fatalf("load atom feed: %s", err)
+	}
+	var atom struct {
+		Entries []struct {
+			ID string `xml:"id"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(atomContent, &atom); err != nil {
+		t.Fatalf("atom feed should be valid xml: %s", err)
+	}
+	if len(atom.Entries) != len(rss.Channel.Items) {
+		t.Errorf("atom feed should have the same items as rss feed, got: %d", len(atom.Entries))
+	}
+
+	jsonContent, err := s.Load(ctx, pages.JSONFeedID)
+	if err != nil {
+		t.Fatalf("load json feed: %s", err)
+	}
+	var jsonFeed struct {
+		Version string `json:"version"`
+		Items   []struct {
+			ContentHTML string `json:"content_html"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(jsonContent, &jsonFeed); err != nil {
+		t.Fatalf("json feed should be valid json: %s", err)
+	}
+	if jsonFeed.Version != "https://jsonfeed.org/version/1.1" || len(jsonFeed.Items) != len(rss.Channel.Items) {
+		t.Errorf("json feed should have the same items as rss feed, got: %+v", jsonFeed)
+	}
+
+	versions := s.Versions()
+	if err := pages.UpdateStore(ctx, p, s, fullContent, runtime.NumCPU()); err != nil {
+		t.Fatalf("second seed: %s", err)
+	}
+	if !s.Versions()[pages.RSSFeedID].Equal(versions[pages.RSSFeedID]) {
+		t.Error("feeds should not be rebuilt when no article changed")
+	}
+
+	// feeds reuse the stored bodies of unchanged articles when another article is edited
+	var mu sync.Mutex
+	retrieved := make(map[string]int)
+	counting := contentProvider{Provider: p, content: func(ctx context.Context, id string) ([]pages.SectionBlock, error) {
+		mu.Lock()
+		retrieved[id]++
+		mu.Unlock()
+		return p.Content(ctx, id)
+	}}
+	if err := srv.Client().Request(ctx, http.MethodPatch, "/pages/article-1", strings.NewReader(`{}`), &struct{}{}); err != nil {
+		t.Fatalf("request update failed: %s", err)
+	}
+	if err := pages.UpdateStore(ctx, counting, s, fullContent, runtime.NumCPU()); err != nil {
+		t.Fatalf("edit seed: %s", err)
+	}
+	if s.Versions()[pages.RSSFeedID].Equal(versions[pages.RSSFeedID]) {
+		t.Error("feeds should be rebuilt when an article changed")
+	}
+	if retrieved["article-1"] == 0 || retrieved["article-2"] != 0 {
+		t.Errorf("only the content of the edited article should be retrieved, got: %v", retrieved)
+	}
+	rssContent, err = s.Load(ctx, pages.RSSFeedID)
+	if err != nil {
+		t.Fatalf("load rss feed: %s", err)
+	}
+	if !strings.Contains(string(rssContent), "&lt;strong&gt;bold&lt;/strong&gt;") || strings.Count(string(rssContent), "&lt;p") < 2 {
+		t.Errorf("full content feed should keep the html of every article")
+	}
+
+	// excerpt feeds are rebuilt when articles change
+	if err := s.Delete(ctx, "first-article"); err != nil {
+		t.Fatalf("delete article: %s", err)
+	}
+	if err := pages.UpdateStore(ctx, p, s, site, runtime.NumCPU()); err != nil {
+		t.Fatalf("excerpt seed: %s", err)
+	}
+	rssContent, err = s.Load(ctx, pages.RSSFeedID)
+	if err != nil {
+		t.Fatalf("load rss feed: %s", err)
+	}
+	if strings.Contains(string(rssContent), "<strong>") || !strings.Contains(string(rssContent), "The first article") {
+		t.Error("excerpt feed should contain article excerpts only")
+	}
+
+	// feeds are rebuilt when the site is described differently although no article changed
+	moved := site
+	moved.URL = "https://blog.example.org/"
+	moved.Title = "Renamed"
+	if err := pages.UpdateStore(ctx, p, s, moved, runtime.NumCPU()); err != nil {
+		t.Fatalf("site seed: %s", err)
+	}
+	rssContent, err = s.Load(ctx, pages.RSSFeedID)
+	if err != nil {
+		t.Fatalf("load rss feed: %s", err)
+	}
+	if !strings.Contains(string(rssContent), "<title>Renamed</title>") ||
+		!strings.Contains(string(rssContent), "<link>https://blog.example.org/blog/first-article</link>") ||
+		strings.Contains(string(rssContent), "https://example.com/") {
+		t.Errorf("feed should be rebuilt with the new site title and url, got: %s", rssContent)
+	}
+
+	moved.FullContentFeeds = true
+	if err := pages.UpdateStore(ctx, p, s, moved, runtime.NumCPU()); err != nil {
+		t.Fatalf("full content seed: %s", err)
+	}
+	jsonContent, err = s.Load(ctx, pages.JSONFeedID)
+	if err != nil {
+		t.Fatalf("load json feed: %s", err)
+	}
+	if !strings.Contains(string(jsonContent), "content_html") {
+		t.Error("feed should be rebuilt with full content when it is turned on")
+	}
+}
+
+func TestSitemap(t *testing.T) {
+	ctx := context.Background()
+
+	p, _ := newProvider(t)
+	s := newRepository(t)
+
+	if err := pages.UpdateStore(ctx, p, s, site, runtime.NumCPU()); err != nil {
+		t.Fatalf("initial seed: %s", err)
+	}
+
+	sitemapContent, err := s.Load(ctx, pages.SitemapID)
+	if err != nil {
+		t.Fatalf("load sitemap: %s", err)
+	}
+	var sitemap struct {
+		URLs []struct {
+			Loc     string `xml:"loc"`
+			LastMod string `xml:"lastmod"`
+		} `xml:"url"`
+	}
+	if err := xml.Unmarshal(sitemapContent, &sitemap); err != nil {
+		t.Fatalf("sitemap should be valid xml: %s", err)
+	}
+
+	lastMods := make(map[string]string)
+	for _, u := range sitemap.URLs {
+		lastMods[u.Loc] = u.LastMod
+	}
+	for _, loc := range []string{"https://example.com/", "https://example.com/blog", "https://example.com/blog/first-article"} {
+		if _, ok := lastMods[loc]; !ok {
+			t.Errorf("sitemap should contain %s, got: %v", loc, lastMods)
+		}
+	}
+	if lastMods["https://example.com/blog/first-article"] != "2023-11-21T10:00:00Z" {
+		t.Errorf("article lastmod should be its last edited time, got: %s", lastMods["https://example.com/blog/first-article"])
+	}
+
+	robots, err := s.Load(ctx, pages.RobotsID)
+	if err != nil {
+		t.Fatalf("load robots: %s", err)
+	}
+	if string(robots) != "User-agent: *\nAllow: /\nSitemap: https://example.com/sitemap.xml\n" {
+		t.Errorf("default robots should allow every crawler and reference the sitemap, got: %q", robots)
+	}
+
+	versions := s.Versions()
+	if err := pages.UpdateStore(ctx, p, s, site, runtime.NumCPU()); err != nil {
+		t.Fatalf("second seed: %s", err)
+	}
+	if !reflect.DeepEqual(s.Versions(), versions) {
+		t.Error("sitemap and robots should not be rebuilt when nothing changed")
+	}
+
+	disallow := site
+	disallow.Robots = "User-agent: *\nDisallow: /files/"
+	if err := pages.UpdateStore(ctx, p, s, disallow, runtime.NumCPU()); err != nil {
+		t.Fatalf("robots seed: %s", err)
+	}
+	robots, err = s.Load(ctx, pages.RobotsID)
+	if err != nil {
+		t.Fatalf("load robots: %s", err)
+	}
+	if string(robots) != "User-agent: *\nDisallow: /files/\nSitemap: https://example.com/sitemap.xml\n" {
+		t.Errorf("robots should be rebuilt from the configured content, got: %q", robots)
+	}
+
+	moved := disallow
+	moved.URL = "https://blog.example.org/"
+	if err := pages.UpdateStore(ctx, p, s, moved, runtime.NumCPU()); err != nil {
+		t.Fatalf("site url seed: %s", err)
+	}
+	sitemapContent, err = s.Load(ctx, pages.SitemapID)
+	if err != nil {
+		t.Fatalf("load sitemap: %s", err)
+	}
+	if !strings.Contains(string(sitemapContent), "<loc>https://blog.example.org/blog/first-article</loc>") ||
+		strings.Contains(string(sitemapContent), "https://example.com/") {
+		t.Errorf("sitemap should be rebuilt with the new site url, got: %s", sitemapContent)
+	}
+}
+
+func TestTagPages(t *testing.T) {
+	ctx := context.Background()
+
+	p, srv := newProvider(t)
+	client := srv.Client()
+	s := newRepository(t)
+
+	if err := pages.UpdateStore(ctx, p, s, site, runtime.NumCPU()); err != nil {
+		t.Fatalf("initial seed: %s", err)
+	}
+
+	goPage, err := s.Load(ctx, pages.TagPageID("go"))
+	if err != nil {
+		t.Fatalf("load go tag page: %s", err)
+	}
+	if !strings.Contains(string(goPage), "/blog/first-article") || !strings.Contains(string(goPage), "/blog/second-article") {
+		t.Error("go tag page should list both articles tagged with it")
+	}
+
+	testingPage, err := s.Load(ctx, pages.TagPageID("testing"))
+	if err != nil {
+		t.Fatalf("load testing tag page: %s", err)
+	}
+	if strings.Contains(string(testingPage), "/blog/second-article") {
+		t.Error("testing tag page should only list articles tagged with it")
+	}
+
+	tagsPage, err := s.Load(ctx, pages.TagsPageID)
+	if err != nil {
+		t.Fatalf("load tags page: %s", err)
+	}
+	for _, href := range []string{`href="/blog/tags/go"`, `href="/blog/tags/testing"`} {
+		if !strings.Contains(string(tagsPage), href) {
+			t.Errorf("tags page should link to %s", href)
+		}
+	}
+
+	versions := s.Versions()
+	if err := pages.UpdateStore(ctx, p, s, site, runtime.NumCPU()); err != nil {
+		t.Fatalf("second seed: %s", err)
+	}
+	if !reflect.DeepEqual(s.Versions(), versions) {
+		t.Error("tag pages should not be rebuilt when membership did not change")
+	}
+
+	untag := `{"properties": {"Tags": {"type": "multi_select", "multi_select": [{"name": "Go"}]}}}`
+	if err := client.Request(ctx, http.MethodPatch, "/pages/article-1", strings.NewReader(untag), &struct{}{}); err != nil {
+		t.Fatalf("request update failed: %s", err)
+	}
+	if err := pages.UpdateStore(ctx, p, s, site, runtime.NumCPU()); err != nil {
+		t.Fatalf("update seed: %s", err)
+	}
+
+	if _, err := s.Load(ctx, pages.TagPageID("testing")); !errors.Is(err, pages.ErrArticleNotFound) {
+		t.Errorf("tag pages without articles should be deleted, got: %v", err)
+	}
+	if s.Versions()[pages.TagPageID("go")].Equal(versions[pages.TagPageID("go")]) {
+		t.Error("go tag page should be rebuilt as its article changed")
+	}
+
+	// articles can't replace tag pages or take the tags route
+	for _, reserved := range []string{pages.TagPageID("go"), "tags"} {
+		slug := fmt.Sprintf(`{"properties": {"Slug": {"type": "rich_text", "rich_text": [{"type": "text", "plain_text": %q, "text": {"content": %q}}]}}}`, reserved, reserved)
+		if err := client.Request(ctx, http.MethodPatch, "/pages/article-2", strings.NewReader(slug), &struct{}{}); err != nil {
+			t.Fatalf("request update failed: %s", err)
+		}
+		if err := pages.UpdateStore(ctx, p, s, site, runtime.NumCPU()); err != nil {
+			t.Fatalf("update seed: %s", err)
+		}
+
+		if _, ok := s.Version("tags"); ok {
+			t.Error("article slugged tags should not be stored")
+		}
+		goPage, err := s.Load(ctx, pages.TagPageID("go"))
+		if err != nil {
+			t.Fatalf("load go tag page: %s", err)
+		}
+		if !strings.Contains(string(goPage), "/blog/first-article") {
+			t.Errorf("go tag page should not be replaced by the article slugged %s", reserved)
+		}
+		if strings.Contains(strings.ToLower(string(goPage)), "second article") {
+			t.Errorf("article slugged %s should not be listed", reserved)
+		}
+	}
+}
+
+func TestPublishing(t *testing.T) {
+	ctx := context.Background()
+
+	p, srv := newProvider(t)
+	client := srv.Client()
+	s := newRepository(t)
+
+	setStatus := func(status string, publishAt time.Time) {
+		t.Helper()
+		body := fmt.Sprintf(`{"properties": {
+			"Status": {"type": "status", "status": {"name": %q}},
+			"PublishAt": {"type": "date", "date": {"start": %q}}
+		}}`, status, publishAt.Format(time.RFC3339))
+		if err := client.Request(ctx, http.MethodPatch, "/pages/article-2", strings.NewReader(body), &struct{}{}); err != nil {
+			t.Fatalf("request update failed: %s", err)
+		}
+		if err := pages.UpdateStore(ctx, p, s, site, runtime.NumCPU()); err != nil {
+			t.Fatalf("seed: %s", err)
+		}
+	}
+	isStored := func() bool {
+		_, err := s.Load(ctx, "second-article")
+		return err == nil
+	}
+
+	setStatus("Draft", time.Now().Add(-time.Hour))
+	if isStored() {
+		t.Error("draft articles should not be published")
+	}
+
+	setStatus("Scheduled", time.Now().Add(time.Hour))
+	if isStored() {
+		t.Error("scheduled articles should not be published before their time")
+	}
+	blog, err := s.Load(ctx, pages.BlogPageID)
+	if err != nil {
+		t.Fatalf("load blog page: %s", err)
+	}
+	if strings.Contains(string(blog), "/blog/second-article") {
+		t.Error("unpublished articles should not be listed")
+	}
+
+	// the time passed
+	setStatus("Scheduled", time.Now().Add(-time.Minute))
+	if !isStored() {
+		t.Error("scheduled articles should be published on the first update after their time")
+	}
+
+	setStatus("Published", time.Time{})
+	if !isStored() {
+		t.Error("published articles should be published")
+	}
+
+	withDrafts := site
+	withDrafts.IncludeDrafts = true
+	setStatus("Draft", time.Time{})
+	if err := pages.UpdateStore(ctx, p, s, withDrafts, runtime.NumCPU()); err != nil {
+		t.Fatalf("seed with drafts: %s", err)
+	}
+	if !isStored() {
+		t.Error("drafts should be published when drafts are included")
+	}
+}
+
+func TestPreview(t *testing.T) {
+	ctx := context.Background()
+
+	p, srv := newProvider(t)
+	client := srv.Client()
+
+	draft := `{"properties": {"Status": {"type": "select", "select": {"name": "Draft"}}}}`
+	if err := client.Request(ctx, http.MethodPatch, "/pages/article-2", strings.NewReader(draft), &struct{}{}); err != nil {
+		t.Fatalf("request update failed: %s", err)
+	}
+
+	page, err := pages.Preview(ctx, p, "article-2")
+	if err != nil {
+		t.Fatalf("should preview draft article: %s", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := page.Render(ctx, buf); err != nil {
+		t.Fatalf("render preview: %s", err)
+	}
+	if !strings.Contains(buf.String(), "SECOND ARTICLE") {
+		t.Error("preview should render the article page")
+	}
+
+	if _, err := pages.Preview(ctx, p, "missing-article"); !errors.Is(err, pages.ErrArticleNotFound) {
+		t.Errorf("previewing missing articles should not be found, got: %v", err)
+	}
+}
+
+func TestUpdater(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var updates int
+	u := pages.NewUpdater(func(ctx context.Context) error {
+		updates++
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+	go u.Run(ctx)
+
+	u.Trigger()
+	<-started
+
+	// triggers during an update are coalesced into a single following update
+	for i := 0; i < 10; i++ {
+		u.Trigger()
+	}
+	release <- struct{}{}
+	<-started
+	release <- struct{}{}
+
+	select {
+	case <-started:
+		t.Fatal("coalesced triggers should run a single update")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if updates != 2 {
+		t.Errorf("should run the triggered and the coalesced update, ran: %d", updates)
+	}
+}
@@ -0,0 +1,30 @@
+package pages
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/a-h/templ"
+)
+
+// Preview renders the article with id live from provider without storing it, unpublished articles included,
+// notion page ids match with or without dashes
+func Preview(ctx context.Context, provider Provider, id string) (templ.Component, error) {
+	atcls, err := provider.Articles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get provider articles: %w", err)
+	}
+
+	id = strings.ReplaceAll(id, "-", "")
+	for _, article := range atcls {
+		if strings.ReplaceAll(article.ID, "-", "") != id {
+			continue
+		}
+
+		page := ArticlePage{article: article, provider: provider}
+		return page.Render(ctx)
+	}
+
+	return nil, fmt.Errorf("preview %s: %w", id, ErrArticleNotFound)
+}
@@ -0,0 +1,104 @@
+package pages
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/so-heil/goblog/business/articles"
+)
+
+const SitemapID = "sitemap"
+const RobotsID = "robots"
+
+// paths sitemap and robots are served under
+const (
+	SitemapPath = "/sitemap.xml"
+	RobotsPath  = "/robots.txt"
+)
+
+// defaultRobots allows every crawler to crawl the whole website
+const defaultRobots = "User-agent: *\nAllow: /\n"
+
+type urlset struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// newSitemapPage creates the sitemap of site listing the about page, blog page, every article and tag page for search
+// engines, it's rebuilt whenever its content changes, e.g. when an article is edited or the site URL is configured
+func newSitemapPage(ctx context.Context, site Site, about *About, atcls []articles.Article, storer Store) (*PrebuiltPage, error) {
+	base := strings.TrimRight(site.URL, "/")
+	lastMod := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+
+	// list pages change whenever one of their articles does
+	var blogUpdate time.Time
+	var articleURLs []sitemapURL
+	tagUpdates := make(map[string]time.Time)
+	for _, article := range atcls {
+		if article.Slug == "" {
+			continue
+		}
+		if article.LastEditedTime.After(blogUpdate) {
+			blogUpdate = article.LastEditedTime
+		}
+		for _, tag := range article.Tags {
+			if path := tagPath(tag); article.LastEditedTime.After(tagUpdates[path]) && TagSlug(tag) != "" {
+				tagUpdates[path] = article.LastEditedTime
+			}
+		}
+		articleURLs = append(articleURLs, sitemapURL{
+			Loc:     fmt.Sprintf("%s/blog/%s", base, article.Slug),
+			LastMod: lastMod(article.LastEditedTime),
+		})
+	}
+
+	set := urlset{URLs: []sitemapURL{
+		{Loc: base + "/", LastMod: lastMod(about.LastEditedTime)},
+		{Loc: base + "/blog", LastMod: lastMod(blogUpdate)},
+	}}
+	set.URLs = append(set.URLs, articleURLs...)
+
+	if len(tagUpdates) > 0 {
+		set.URLs = append(set.URLs, sitemapURL{Loc: base + "/blog/tags", LastMod: lastMod(blogUpdate)})
+		tagPaths := make([]string, 0, len(tagUpdates))
+		for path := range tagUpdates {
+			tagPaths = append(tagPaths, path)
+		}
+		sort.Strings(tagPaths)
+		for _, path := range tagPaths {
+			set.URLs = append(set.URLs, sitemapURL{Loc: base + path, LastMod: lastMod(tagUpdates[path])})
+		}
+	}
+
+	content, err := encodeXML(set)
+	if err != nil {
+		return nil, fmt.Errorf("encode sitemap: %w", err)
+	}
+
+	return newPrebuiltPage(ctx, storer, SitemapID, content)
+}
+
+// newRobotsPage creates the robots.txt of site pointing crawlers to the sitemap
+func newRobotsPage(ctx context.Context, site Site, storer Store) (*PrebuiltPage, error) {
+	robots := site.Robots
+	if robots == "" {
+		robots = defaultRobots
+	}
+	content := fmt.Sprintf("%s\nSitemap: %s%s\n", strings.TrimRight(robots, "\n"), strings.TrimRight(site.URL, "/"), SitemapPath)
+
+	return newPrebuiltPage(ctx, storer, RobotsID, []byte(content))
+}
@@ -0,0 +1,113 @@
+package pages
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/so-heil/goblog/business/articles"
+	"github.com/so-heil/goblog/business/templates/components/breadcrumb"
+	"github.com/so-heil/goblog/business/templates/pages/blog"
+	"github.com/so-heil/goblog/foundation/slug"
+)
+
+const TagsPageID = "tags_page"
+
+// TagPagePrefix prefixes the tag slug in the ID of a tag page
+const TagPagePrefix = "tag_page_"
+
+// TagPageID returns the ID of the page listing articles with the tag slug
+func TagPageID(slug string) string {
+	return TagPagePrefix + slug
+}
+
+// TagSlug returns the URL-safe slug of tag, tags with the same slug share a page
+func TagSlug(tag string) string {
+	return slug.Make(tag)
+}
+
+// tagPath is the path of the page listing articles with tag
+func tagPath(tag string) string {
+	return fmt.Sprintf("/blog/tags/%s", TagSlug(tag))
+}
+
+// tagGroup is a tag with the articles tagged with it
+type tagGroup struct {
+	tag      blog.Tag
+	slug     string
+	articles []blog.Article
+}
+
+// newTagPages renders a page per tag listing its articles, newest first, and the tags page listing every tag,
+// they are updated only when the tag membership or the listed articles change
+func newTagPages(ctx context.Context, atcls []articles.Article, storer Store) ([]Page, error) {
+	sorted := make([]articles.Article, 0, len(atcls))
+	for _, article := range atcls {
+		if article.Slug != "" {
+			sorted = append(sorted, article)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].WrittenAt.After(sorted[j].WrittenAt)
+	})
+
+	groups := make(map[string]*tagGroup)
+	for _, article := range sorted {
+		for _, tag := range article.Tags {
+			slug := TagSlug(tag)
+			if slug == "" {
+				continue
+			}
+			group, ok := groups[slug]
+			if !ok {
+				group = &tagGroup{tag: blog.Tag{Name: tag, Href: tagPath(tag)}, slug: slug}
+				groups[slug] = group
+			}
+			// an article tagged twice with the same slug is listed once
+			if n := len(group.articles); n > 0 && group.articles[n-1].Slug == article.Slug {
+				continue
+			}
+			group.articles = append(group.articles, toBlogArticle(article))
+			group.tag.Count++
+		}
+	}
+
+	tags := make([]blog.Tag, 0, len(groups))
+	var tagPages []Page
+	for _, group := range groups {
+		tags = append(tags, group.tag)
+
+		content, err := pageContent(ctx, blog.TagPage([]breadcrumb.Link{
+			{Title: "BLOG", Href: "/blog"},
+			{Title: group.tag.Name, Href: group.tag.Href},
+		}, group.tag, group.articles))
+		if err != nil {
+			return nil, fmt.Errorf("tag %s: %w", group.tag.Name, err)
+		}
+
+		page, err := newPrebuiltPage(ctx, storer, TagPageID(group.slug), content)
+		if err != nil {
+			return nil, err
+		}
+		tagPages = append(tagPages, page)
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return strings.ToLower(tags[i].Name) < strings.ToLower(tags[j].Name)
+	})
+	content, err := pageContent(ctx, blog.TagsPage([]breadcrumb.Link{
+		{Title: "BLOG", Href: "/blog"},
+		{Title: "TAGS", Href: "/blog/tags"},
+	}, tags))
+	if err != nil {
+		return nil, fmt.Errorf("tags: %w", err)
+	}
+
+	page, err := newPrebuiltPage(ctx, storer, TagsPageID, content)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(tagPages, page), nil
+}
@@ -1,21 +1,52 @@
 package pages
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"time"
+
+	"github.com/so-heil/goblog/business/articles"
 )
 
-// UpdateStore seeds the Store with all absent and outdated article pages and blog page from the Provider with the specified maxWorkers as concurrent workers
-func UpdateStore(provider Provider, storer Store, maxWorkers int) error {
-	atcls, err := provider.Articles()
+// UpdateStore seeds the Store with all absent and outdated article pages, blog page, tag pages, feeds, sitemap and robots of site from the Provider with the specified maxWorkers
+// as concurrent workers, the update is abandoned when ctx is done
+func UpdateStore(ctx context.Context, provider Provider, storer Store, site Site, maxWorkers int) error {
+	atcls, err := provider.Articles(ctx)
 	if err != nil {
 		return fmt.Errorf("get provider articles: %w", err)
 	}
-	aboutData, err := provider.AboutPage()
+	if !site.IncludeDrafts {
+		atcls = published(atcls, time.Now())
+	}
+	atcls = servable(atcls)
+	aboutData, err := provider.AboutPage(ctx)
 	if err != nil {
 		return fmt.Errorf("get provider about page: %w", err)
 	}
+	storedBuild, err := storer.Build(ctx)
+	if err != nil {
+		return fmt.Errorf("stored build: %w", err)
+	}
+	robots, err := newRobotsPage(ctx, site, storer)
+	if err != nil {
+		return fmt.Errorf("robots page: %w", err)
+	}
+	tagPages, err := newTagPages(ctx, atcls, storer)
+	if err != nil {
+		return fmt.Errorf("tag pages: %w", err)
+	}
+	sitemap, err := newSitemapPage(ctx, site, &aboutData, atcls, storer)
+	if err != nil {
+		return fmt.Errorf("sitemap page: %w", err)
+	}
+	// stored article bodies rendered by another build may use markup that changed
+	feedPages, err := newFeedPages(ctx, site, atcls, provider, storer, storedBuild == site.Build)
+	if err != nil {
+		return fmt.Errorf("feed pages: %w", err)
+	}
 
 	// counting semaphore to control the number of workers
 	sem := make(chan struct{}, maxWorkers)
@@ -42,13 +73,19 @@ func UpdateStore(provider Provider, storer Store, maxWorkers int) error {
 
 				id := page.ID()
 				version := page.Version()
-				content, err := build(page)
+				content, err := build(ctx, page, storer)
 				if err != nil {
 					wErr = fmt.Errorf("build page[%s:%s]: %w", id, version.String(), err)
 					return
 				}
 
-				if err := storer.Store(page.ID(), content, page.Version()); err != nil {
+				encoded, err := compress(content)
+				if err != nil {
+					wErr = fmt.Errorf("compress page[%s:%s]: %w", id, version, err)
+					return
+				}
+
+				if err := storer.Store(ctx, page.ID(), content, encoded, page.Version()); err != nil {
 					wErr = fmt.Errorf("store page[%s:%s]: %w", id, version, err)
 					return
 				}
@@ -56,7 +93,12 @@ func UpdateStore(provider Provider, storer Store, maxWorkers int) error {
 		}
 	}
 
-	versionsBeforeUpdate := storer.Versions()
+	storedVersions := storer.Versions()
+	versionsBeforeUpdate := storedVersions
+	// pages rendered by another build may use markup and assets that changed, they are rebuilt as if they were absent
+	if storedBuild != site.Build {
+		versionsBeforeUpdate = make(map[string]time.Time)
+	}
 	for _, article := range atcls {
 		if article.Slug == "" {
 			continue
@@ -68,23 +110,42 @@ func UpdateStore(provider Provider, storer Store, maxWorkers int) error {
 			versions: versionsBeforeUpdate,
 		})
 	}
+	// only article pages are built so far, building the other pages doesn't change the lists of articles
+	anyArticleUpdated := workers > 0
 	updateIfChanged(&AboutPage{
 		data:     &aboutData,
 		provider: provider,
 		versions: versionsBeforeUpdate,
 	})
 	updateIfChanged(&NotFoundPage{versions: versionsBeforeUpdate})
+	updateIfChanged(&ServerErrorPage{versions: versionsBeforeUpdate})
+	updateIfChanged(robots)
+	updateIfChanged(sitemap)
+	for _, page := range tagPages {
+		updateIfChanged(page)
+	}
+	for _, page := range feedPages {
+		updateIfChanged(page)
+	}
+
+	// the blog page is rebuilt when an article page was built or an article is gone
+	for id := range storedVersions {
+		if _, ok := pagesAfterUpdate[id]; !ok && !isListPage(id) {
+			anyArticleUpdated = true
+		}
+	}
+
 	updateIfChanged(&BlogPage{
 		articles:          atcls,
-		anyArticleUpdated: workers > 0 || len(pagesAfterUpdate) != len(versionsBeforeUpdate)-1,
+		anyArticleUpdated: anyArticleUpdated,
 	})
 
 	var buildErr error
 
 	var deleted int
-	for id := range versionsBeforeUpdate {
+	for id := range storedVersions {
 		if _, ok := pagesAfterUpdate[id]; !ok {
-			if err := storer.Delete(id); err != nil {
+			if err := storer.Delete(ctx, id); err != nil {
 				// an early return after initialization of workers would cause goroutine leak
 				buildErr = err
 				continue
@@ -94,16 +155,74 @@ func UpdateStore(provider Provider, storer Store, maxWorkers int) error {
 	}
 
 	for i := 0; i < workers; i++ {
-		err := <-workerErrs
-		if err != nil {
-			buildErr = errors.New(buildErr.Error() + "\n" + err.Error())
-			continue
+		if err := <-workerErrs; err != nil {
+			buildErr = errors.Join(buildErr, err)
 		}
 	}
 	if buildErr != nil {
 		return buildErr
 	}
 
-	log.Printf("updateStore: updated+added %d, deleted %d, total currently stored: %d\n", workers, deleted, len(storer.Versions()))
+	// the build is stored once every page is rendered with it, a failed update is retried with a full rebuild
+	if storedBuild != site.Build {
+		if err := storer.StoreBuild(ctx, site.Build); err != nil {
+			return fmt.Errorf("store build: %w", err)
+		}
+	}
+
+	// files are only left unreferenced by pages that were rebuilt or deleted
+	var deletedFiles int
+	if workers > 0 || deleted > 0 {
+		if deletedFiles, err = deleteUnreferencedFiles(ctx, storer); err != nil {
+			return fmt.Errorf("delete unreferenced files: %w", err)
+		}
+	}
+
+	log.Printf("updateStore: updated+added %d, deleted %d, deleted files %d, total currently stored: %d\n", workers, deleted, deletedFiles, len(storer.Versions()))
 	return nil
 }
+
+// published returns the articles published at now, scheduled articles are published by the first update after their time
+func published(atcls []articles.Article, now time.Time) []articles.Article {
+	var publishedArticles []articles.Article
+	for _, article := range atcls {
+		if article.IsPublished(now) {
+			publishedArticles = append(publishedArticles, article)
+		}
+	}
+	return publishedArticles
+}
+
+// servable returns the articles that can be served under their slug, articles whose slug is reserved are left out
+// as they would replace another page
+func servable(atcls []articles.Article) []articles.Article {
+	var servableArticles []articles.Article
+	for _, article := range atcls {
+		if reservedSlug(article.Slug) {
+			log.Printf("updateStore: article %s is left out: slug %q is reserved\n", article.ID, article.Slug)
+			continue
+		}
+		servableArticles = append(servableArticles, article)
+	}
+	return servableArticles
+}
+
+// reservedSlug reports whether slug is taken by the tags route under /blog/ or the ID of another page in Store,
+// article pages are stored by their slug
+func reservedSlug(slug string) bool {
+	switch slug {
+	case "tags", BlogPageID, AboutPageID, NotFoundPageID, ServerErrorPageID, TagsPageID,
+		RSSFeedID, AtomFeedID, JSONFeedID, SitemapID, RobotsID:
+		return true
+	}
+	return strings.HasPrefix(slug, TagPagePrefix)
+}
+
+// isListPage reports whether id is a page listing articles
+func isListPage(id string) bool {
+	switch id {
+	case BlogPageID, RSSFeedID, AtomFeedID, JSONFeedID, SitemapID:
+		return true
+	}
+	return false
+}
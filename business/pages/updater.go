@@ -0,0 +1,44 @@
+package pages
+
+import (
+	"context"
+	"log"
+)
+
+// Updater runs store updates one at a time as they're triggered, triggers while an update is in flight are coalesced
+// into a single following update so edits made during an update are not missed
+type Updater struct {
+	update  func(ctx context.Context) error
+	trigger chan struct{}
+}
+
+// NewUpdater creates an Updater running update, e.g. an UpdateStore call, on every trigger
+func NewUpdater(update func(ctx context.Context) error) *Updater {
+	return &Updater{
+		update: update,
+		// a pending trigger is kept while an update runs, any other is coalesced into it
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+// Trigger requests an update without waiting for it
+func (u *Updater) Trigger() {
+	select {
+	case u.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run runs triggered updates until ctx is done, ctx is passed to every update
+func (u *Updater) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-u.trigger:
+			if err := u.update(ctx); err != nil {
+				log.Printf("updater: %s\n", err)
+			}
+		}
+	}
+}
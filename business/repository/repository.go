@@ -1,7 +1,9 @@
-// Package repository provides an in-memory repository for articles
+// Package repository provides a badger backed repository for pages
 package repository
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"sync"
@@ -13,23 +15,122 @@ import (
 
 const prefix = "repository_article_"
 
+// metaPrefix is the key prefix page metadata is stored under, separate from the page content
+const metaPrefix = "repository_meta_"
+
+// filePrefix is the key prefix files referenced by pages are stored under
+const filePrefix = "repository_file_"
+
+// buildKey is the key the build stored pages were rendered with is stored under
+const buildKey = "repository_build"
+
+// bodyPrefix is the key prefix the rendered bodies of article pages are stored under
+const bodyPrefix = "repository_body_"
+
 func key(id string) []byte {
 	return []byte(fmt.Sprintf("%s%s", prefix, id))
 }
 
+func metaKey(id string) []byte {
+	return []byte(fmt.Sprintf("%s%s", metaPrefix, id))
+}
+
+// encodedPrefix is the key prefix pre-compressed variants of pages are stored under
+const encodedPrefix = "repository_encoded_"
+
+// encodedKeyPrefix prefixes the keys of every variant of the page with id, ids and encodings are separated by a null byte
+// so no page id is a prefix of another
+func encodedKeyPrefix(id string) []byte {
+	return []byte(fmt.Sprintf("%s%s\x00", encodedPrefix, id))
+}
+
+func encodedKey(id string, encoding string) []byte {
+	return append(encodedKeyPrefix(id), encoding...)
+}
+
+func fileKey(name string) []byte {
+	return []byte(fmt.Sprintf("%s%s", filePrefix, name))
+}
+
+func bodyKey(id string) []byte {
+	return []byte(fmt.Sprintf("%s%s", bodyPrefix, id))
+}
+
+// meta is the page metadata persisted alongside its content
+type meta struct {
+	Version time.Time `json:"version"`
+}
+
+// body is the rendered body of an article page persisted with the version it was rendered at
+type body struct {
+	Version time.Time `json:"version"`
+	Content []byte    `json:"content"`
+}
+
 type Repository struct {
 	db       *badger.DB
 	versions sync.Map
 }
 
-func New(db *badger.DB) *Repository {
-	return &Repository{db: db}
+// New creates a Repository on top of db and loads the metadata of already stored pages
+func New(db *badger.DB) (*Repository, error) {
+	repo := &Repository{db: db}
+	if err := repo.loadMeta(); err != nil {
+		return nil, fmt.Errorf("load pages metadata: %w", err)
+	}
+	return repo, nil
+}
+
+// loadMeta reads every stored page metadata from db into memory
+func (repo *Repository) loadMeta() error {
+	return repo.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(metaPrefix)
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			id := string(item.Key()[len(metaPrefix):])
+
+			var m meta
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &m)
+			}); err != nil {
+				return fmt.Errorf("decode meta of page[%s]: %w", id, err)
+			}
+			repo.versions.Store(id, m.Version)
+		}
+
+		return nil
+	})
 }
 
-func (repo *Repository) Store(id string, content []byte, version time.Time) error {
+// Store stores the content, its variants and version of the page with id in a single transaction
+// so the variants never go stale, variants of the previous content that are not in encoded are removed
+func (repo *Repository) Store(ctx context.Context, id string, content []byte, encoded map[string][]byte, version time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("store article content: %w", err)
+	}
+
+	m, err := json.Marshal(meta{Version: version})
+	if err != nil {
+		return fmt.Errorf("encode article meta: %w", err)
+	}
+
 	if err := repo.db.Update(func(txn *badger.Txn) error {
-		err := txn.Set(key(id), content)
-		return err
+		if err := deleteEncoded(txn, id); err != nil {
+			return err
+		}
+		for encoding, variant := range encoded {
+			if err := txn.Set(encodedKey(id, encoding), variant); err != nil {
+				return err
+			}
+		}
+		if err := txn.Set(key(id), content); err != nil {
+			return err
+		}
+		return txn.Set(metaKey(id), m)
 	}); err != nil {
 		return fmt.Errorf("store article content: %w", err)
 	}
@@ -38,7 +139,11 @@ func (repo *Repository) Store(id string, content []byte, version time.Time) erro
 	return nil
 }
 
-func (repo *Repository) Load(id string) ([]byte, error) {
+func (repo *Repository) Load(ctx context.Context, id string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("retrieve from db: %w", err)
+	}
+
 	var content []byte
 	err := repo.db.View(func(txn *badger.Txn) error {
 		item, err := txn.Get(key(id))
@@ -64,16 +169,85 @@ func (repo *Repository) Load(id string) ([]byte, error) {
 	return content, nil
 }
 
-func (repo *Repository) Delete(id string) error {
+// LoadEncoded loads the variant of the page with id compressed with encoding
+func (repo *Repository) LoadEncoded(ctx context.Context, id string, encoding string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("retrieve encoded from db: %w", err)
+	}
+
+	var content []byte
+	err := repo.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(encodedKey(id, encoding))
+		if err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return pages.ErrArticleNotFound
+			}
+
+			return fmt.Errorf("get article[%s] %s variant from db: %w", id, encoding, err)
+		}
+
+		if content, err = item.ValueCopy(nil); err != nil {
+			return fmt.Errorf("value copy item: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("retrieve encoded from db: %w", err)
+	}
+
+	return content, nil
+}
+
+// deleteEncoded deletes every variant of the page with id in txn
+func deleteEncoded(txn *badger.Txn, id string) error {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = encodedKeyPrefix(id)
+	opts.PrefetchValues = false
+	it := txn.NewIterator(opts)
+
+	var keys [][]byte
+	for it.Rewind(); it.Valid(); it.Next() {
+		keys = append(keys, it.Item().KeyCopy(nil))
+	}
+	// keys can't be deleted while iterating
+	it.Close()
+
+	for _, k := range keys {
+		if err := txn.Delete(k); err != nil {
+			return fmt.Errorf("delete article[%s] variant: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func (repo *Repository) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("delete article[%s]: %w", id, err)
+	}
+
 	err := repo.db.Update(func(txn *badger.Txn) error {
+		if err := deleteEncoded(txn, id); err != nil {
+			return err
+		}
 		if err := txn.Delete(key(id)); err != nil {
 			return fmt.Errorf("delete article[%s]: %w", id, err)
 		}
+		if err := txn.Delete(metaKey(id)); err != nil {
+			return fmt.Errorf("delete article[%s] meta: %w", id, err)
+		}
+		if err := txn.Delete(bodyKey(id)); err != nil {
+			return fmt.Errorf("delete article[%s] body: %w", id, err)
+		}
 		return nil
 	})
+	if err != nil {
+		return err
+	}
 	repo.versions.Delete(id)
 
-	return err
+	return nil
 }
 
 func (repo *Repository) Versions() map[string]time.Time {
@@ -86,3 +260,179 @@ func (repo *Repository) Versions() map[string]time.Time {
 	})
 	return versions
 }
+
+func (repo *Repository) Version(id string) (time.Time, bool) {
+	version, ok := repo.versions.Load(id)
+	if !ok {
+		return time.Time{}, false
+	}
+	return version.(time.Time), true
+}
+
+// Build loads the build stored pages were rendered with, it is empty if none is stored
+func (repo *Repository) Build(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("retrieve build from db: %w", err)
+	}
+
+	var build []byte
+	err := repo.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(buildKey))
+		if err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		build, err = item.ValueCopy(nil)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("retrieve build from db: %w", err)
+	}
+
+	return string(build), nil
+}
+
+// StoreBuild stores the build stored pages were rendered with
+func (repo *Repository) StoreBuild(ctx context.Context, build string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("store build: %w", err)
+	}
+
+	if err := repo.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(buildKey), []byte(build))
+	}); err != nil {
+		return fmt.Errorf("store build: %w", err)
+	}
+
+	return nil
+}
+
+// StoreBody stores the rendered body of the article page with id at version
+func (repo *Repository) StoreBody(ctx context.Context, id string, content []byte, version time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("store article body: %w", err)
+	}
+
+	b, err := json.Marshal(body{Version: version, Content: content})
+	if err != nil {
+		return fmt.Errorf("encode article body: %w", err)
+	}
+
+	if err := repo.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(bodyKey(id), b)
+	}); err != nil {
+		return fmt.Errorf("store article body: %w", err)
+	}
+
+	return nil
+}
+
+// LoadBody loads the rendered body of the article page with id and the version it was rendered at
+func (repo *Repository) LoadBody(ctx context.Context, id string) ([]byte, time.Time, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, time.Time{}, fmt.Errorf("retrieve body from db: %w", err)
+	}
+
+	var b body
+	err := repo.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(bodyKey(id))
+		if err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return pages.ErrArticleNotFound
+			}
+
+			return fmt.Errorf("get article[%s] body from db: %w", id, err)
+		}
+
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &b)
+		})
+	})
+
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("retrieve body from db: %w", err)
+	}
+
+	return b.Content, b.Version, nil
+}
+
+func (repo *Repository) StoreFile(ctx context.Context, name string, content []byte) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("store file: %w", err)
+	}
+
+	if err := repo.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(fileKey(name), content)
+	}); err != nil {
+		return fmt.Errorf("store file: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *Repository) LoadFile(ctx context.Context, name string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("retrieve file from db: %w", err)
+	}
+
+	var content []byte
+	err := repo.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(fileKey(name))
+		if err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return pages.ErrFileNotFound
+			}
+
+			return fmt.Errorf("get file[%s] from db: %w", name, err)
+		}
+
+		if content, err = item.ValueCopy(nil); err != nil {
+			return fmt.Errorf("value copy item: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("retrieve file from db: %w", err)
+	}
+
+	return content, nil
+}
+
+func (repo *Repository) DeleteFile(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("delete file[%s]: %w", name, err)
+	}
+
+	if err := repo.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(fileKey(name))
+	}); err != nil {
+		return fmt.Errorf("delete file[%s]: %w", name, err)
+	}
+
+	return nil
+}
+
+func (repo *Repository) Files() ([]string, error) {
+	var names []string
+	err := repo.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(filePrefix)
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			names = append(names, string(it.Item().Key()[len(filePrefix):]))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list files: %w", err)
+	}
+	return names, nil
+}
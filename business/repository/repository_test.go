@@ -1,6 +1,7 @@
 package repository_test
 
 import (
+	"context"
 	"crypto/rand"
 	"errors"
 	"os"
@@ -14,6 +15,8 @@ import (
 )
 
 func TestRepository(t *testing.T) {
+	ctx := context.Background()
+
 	dir, err := os.MkdirTemp("", "badger-test")
 	if err != nil {
 		t.Fatalf("mkdir temp: %s", err)
@@ -36,7 +39,10 @@ func TestRepository(t *testing.T) {
 		}
 	}(db)
 
-	s := repository.New(db)
+	s, err := repository.New(db)
+	if err != nil {
+		t.Fatalf("new repository: %s", err)
+	}
 
 	content := make([]byte, 10*1024)
 	if _, err := rand.Read(content); err != nil {
@@ -44,7 +50,7 @@ func TestRepository(t *testing.T) {
 	}
 
 	testID := "test_id"
-	if err := s.Store(testID, content, time.Now()); err != nil {
+	if err := s.Store(ctx, testID, content, nil, time.Now()); err != nil {
 		t.Fatalf("store content: %s", err)
 	}
 
@@ -59,13 +65,20 @@ func TestRepository(t *testing.T) {
 		}
 	}
 
+	if version, ok := s.Version(testID); !ok || !version.Equal(versions[testID]) {
+		t.Errorf("version of %s should be %s, got: %s", testID, versions[testID], version)
+	}
+	if _, ok := s.Version("some_random_id"); ok {
+		t.Error("absent pages should have no version")
+	}
+
 	content2 := make([]byte, 10*1024)
 	if _, err := rand.Read(content2); err != nil {
 		t.Fatalf("create random content: %s", err)
 	}
 
 	testID2 := "test_id2"
-	if err := s.Store(testID2, content2, time.Now()); err != nil {
+	if err := s.Store(ctx, testID2, content2, nil, time.Now()); err != nil {
 		t.Fatalf("store content: %s", err)
 	}
 
@@ -73,7 +86,7 @@ func TestRepository(t *testing.T) {
 		t.Errorf("should have 2 record, has: %d", len(versions))
 	}
 
-	retrieved, err := s.Load(testID)
+	retrieved, err := s.Load(ctx, testID)
 	if err != nil {
 		t.Fatalf("retrieve content: %s", err)
 	}
@@ -82,21 +95,36 @@ func TestRepository(t *testing.T) {
 		t.Error("same content should be retrieved from db")
 	}
 
-	if _, err := s.Load("some_random_id"); err != nil {
+	reopened, err := repository.New(db)
+	if err != nil {
+		t.Fatalf("reopen repository: %s", err)
+	}
+
+	reopenedVersions := reopened.Versions()
+	if len(reopenedVersions) != 2 {
+		t.Errorf("should load 2 records from db, has: %d", len(reopenedVersions))
+	}
+	for id, version := range s.Versions() {
+		if !reopenedVersions[id].Equal(version) {
+			t.Errorf("version of %s should be loaded from db, got: %s, want: %s", id, reopenedVersions[id], version)
+		}
+	}
+
+	if _, err := s.Load(ctx, "some_random_id"); err != nil {
 		if !errors.Is(err, pages.ErrArticleNotFound) {
 			t.Fatalf("should yeild not found error, got: %s", err)
 		}
 	}
 
-	if err := s.Delete(testID); err != nil {
+	if err := s.Delete(ctx, testID); err != nil {
 		t.Fatalf("delete test 1 content: %s", err)
 	}
 
-	if err := s.Delete(testID2); err != nil {
+	if err := s.Delete(ctx, testID2); err != nil {
 		t.Fatalf("delete test 2 content: %s", err)
 	}
 
-	if _, err := s.Load(testID); err != nil {
+	if _, err := s.Load(ctx, testID); err != nil {
 		if !errors.Is(err, pages.ErrArticleNotFound) {
 			t.Fatalf("should yeild not found error, got: %s", err)
 		}
@@ -105,4 +133,121 @@ func TestRepository(t *testing.T) {
 	if len(s.Versions()) != 0 {
 		t.Errorf("should have 0 record, has: %d", len(versions))
 	}
+
+	reopened, err = repository.New(db)
+	if err != nil {
+		t.Fatalf("reopen repository: %s", err)
+	}
+
+	if len(reopened.Versions()) != 0 {
+		t.Errorf("deleted versions should not be loaded from db, has: %d", len(reopened.Versions()))
+	}
+
+	if err := s.StoreFile(ctx, "image.png", content); err != nil {
+		t.Fatalf("store file: %s", err)
+	}
+
+	file, err := s.LoadFile(ctx, "image.png")
+	if err != nil {
+		t.Fatalf("load file: %s", err)
+	}
+	if !reflect.DeepEqual(file, content) {
+		t.Error("same file should be retrieved from db")
+	}
+
+	if files, err := s.Files(); err != nil || len(files) != 1 || files[0] != "image.png" {
+		t.Errorf("should list the stored file, got: %v, %v", files, err)
+	}
+
+	if len(s.Versions()) != 0 {
+		t.Errorf("files should not be listed as versions, has: %d", len(s.Versions()))
+	}
+
+	if _, err := s.LoadFile(ctx, "missing.png"); !errors.Is(err, pages.ErrFileNotFound) {
+		t.Fatalf("should yeild file not found error, got: %v", err)
+	}
+
+	if err := s.DeleteFile(ctx, "image.png"); err != nil {
+		t.Fatalf("delete file: %s", err)
+	}
+	if _, err := s.LoadFile(ctx, "image.png"); !errors.Is(err, pages.ErrFileNotFound) {
+		t.Errorf("deleted file should not be found, got: %v", err)
+	}
+	if files, err := s.Files(); err != nil || len(files) != 0 {
+		t.Errorf("deleted file should not be listed, got: %v, %v", files, err)
+	}
+
+	if build, err := s.Build(ctx); err != nil || build != "" {
+		t.Fatalf("build should be empty before one is stored, got: %q, %v", build, err)
+	}
+	if err := s.StoreBuild(ctx, "v1"); err != nil {
+		t.Fatalf("store build: %s", err)
+	}
+	if build, err := s.Build(ctx); err != nil || build != "v1" {
+		t.Errorf("stored build should be loaded, got: %q, %v", build, err)
+	}
+	if len(s.Versions()) != 0 {
+		t.Errorf("build should not be listed as a version, has: %d", len(s.Versions()))
+	}
+
+	encoded := map[string][]byte{"gzip": content2, "br": content}
+	if err := s.Store(ctx, testID, content, encoded, time.Now()); err != nil {
+		t.Fatalf("store content with variants: %s", err)
+	}
+
+	for encoding, variant := range encoded {
+		retrieved, err := s.LoadEncoded(ctx, testID, encoding)
+		if err != nil {
+			t.Fatalf("load %s variant: %s", encoding, err)
+		}
+		if !reflect.DeepEqual(retrieved, variant) {
+			t.Errorf("same %s variant should be retrieved from db", encoding)
+		}
+	}
+
+	// variants missing from a later store are removed with the stale content
+	if err := s.Store(ctx, testID, content2, map[string][]byte{"gzip": content}, time.Now()); err != nil {
+		t.Fatalf("store content with variants: %s", err)
+	}
+	if _, err := s.LoadEncoded(ctx, testID, "br"); !errors.Is(err, pages.ErrArticleNotFound) {
+		t.Errorf("stale variant should be removed, got: %v", err)
+	}
+
+	if err := s.Delete(ctx, testID); err != nil {
+		t.Fatalf("delete test content: %s", err)
+	}
+	if _, err := s.LoadEncoded(ctx, testID, "gzip"); !errors.Is(err, pages.ErrArticleNotFound) {
+		t.Errorf("variants should be deleted with the page, got: %v", err)
+	}
+	if _, err := s.LoadEncoded(ctx, testID+"2", "gzip"); !errors.Is(err, pages.ErrArticleNotFound) {
+		t.Errorf("variants should not be shared by ids sharing a prefix, got: %v", err)
+	}
+
+	if _, _, err := s.LoadBody(ctx, testID); !errors.Is(err, pages.ErrArticleNotFound) {
+		t.Fatalf("absent body should yeild not found error, got: %v", err)
+	}
+	bodyVersion := time.Date(2023, 11, 21, 10, 0, 0, 0, time.UTC)
+	if err := s.StoreBody(ctx, testID, content, bodyVersion); err != nil {
+		t.Fatalf("store body: %s", err)
+	}
+	body, version, err := s.LoadBody(ctx, testID)
+	if err != nil {
+		t.Fatalf("load body: %s", err)
+	}
+	if !reflect.DeepEqual(body, content) || !version.Equal(bodyVersion) {
+		t.Errorf("same body should be retrieved from db with its version, got version: %s", version)
+	}
+	if len(s.Versions()) != 0 {
+		t.Errorf("bodies should not be listed as versions, has: %d", len(s.Versions()))
+	}
+
+	if err := s.Store(ctx, testID, content, nil, time.Now()); err != nil {
+		t.Fatalf("store content: %s", err)
+	}
+	if err := s.Delete(ctx, testID); err != nil {
+		t.Fatalf("delete test content: %s", err)
+	}
+	if _, _, err := s.LoadBody(ctx, testID); !errors.Is(err, pages.ErrArticleNotFound) {
+		t.Errorf("body should be deleted with the page, got: %v", err)
+	}
 }
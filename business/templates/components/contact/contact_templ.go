@@ -10,6 +10,8 @@ import "context"
 import "io"
 import "bytes"
 
+import "github.com/so-heil/goblog/business/assets"
+
 func Contact() templ.Component {
 	return templ.ComponentFunc(func(ctx context.Context, templ_7745c5c3_W io.Writer) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templ_7745c5c3_W.(*bytes.Buffer)
@@ -23,7 +25,31 @@ func Contact() templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("<div class=\"fixed bottom-0 left-[4.2rem] flex flex-col items-center gap-6\"><a href=\"\"><img src=\"/static/images/github-mark-white.svg\" class=\"w-6 h-6 opacity-40 hover:opacity-100 transition-all\"></a> <a href=\"\"><img src=\"/static/images/linkedin.svg\" class=\"w-6 h-6 opacity-40 hover:opacity-100 transition-all\"></a> <a href=\"\"><img src=\"/static/images/telegram.svg\" class=\"w-6 h-6 opacity-40 hover:opacity-100 transition-all\"></a><div class=\"w-[1px] h-[32px] bg-white opacity-40\"></div></div>")
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("<div class=\"fixed bottom-0 left-[4.2rem] flex flex-col items-center gap-6\"><a href=\"\"><img src=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(assets.URL("/static/images/github-mark-white.svg")))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("\" class=\"w-6 h-6 opacity-40 hover:opacity-100 transition-all\"></a> <a href=\"\"><img src=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(assets.URL("/static/images/linkedin.svg")))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("\" class=\"w-6 h-6 opacity-40 hover:opacity-100 transition-all\"></a> <a href=\"\"><img src=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(assets.URL("/static/images/telegram.svg")))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("\" class=\"w-6 h-6 opacity-40 hover:opacity-100 transition-all\"></a><div class=\"w-[1px] h-[32px] bg-white opacity-40\"></div></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
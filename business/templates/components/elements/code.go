@@ -0,0 +1,111 @@
+package elements
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// CodeOptions change how a code block is rendered
+type CodeOptions struct {
+	// LineNumbers shows the number of every line next to the code
+	LineNumbers bool
+	// HighlightLines are the inclusive ranges of 1-based line numbers emphasized in the code
+	HighlightLines [][2]int
+}
+
+// ParseCodeOptions parses code block options like "linenos hl_lines=2-3,5" turning on line numbers and
+// highlighting lines 2, 3 and 5, surrounding braces are ignored and unknown options are skipped
+func ParseCodeOptions(s string) CodeOptions {
+	var options CodeOptions
+	for _, option := range strings.Fields(strings.Trim(strings.TrimSpace(s), "{}")) {
+		name, value, _ := strings.Cut(option, "=")
+		switch name {
+		case "linenos":
+			options.LineNumbers = value != "false"
+		case "hl_lines":
+			for _, r := range strings.Split(strings.Trim(value, `"'[]`), ",") {
+				start, end, isRange := strings.Cut(r, "-")
+				from, err := strconv.Atoi(start)
+				if err != nil {
+					continue
+				}
+				to := from
+				if isRange {
+					if to, err = strconv.Atoi(end); err != nil || to < from {
+						continue
+					}
+				}
+				options.HighlightLines = append(options.HighlightLines, [2]int{from, to})
+			}
+		}
+	}
+	return options
+}
+
+// codeStyle colors highlighted code, its background matches the rest of the website
+var codeStyle = func() *chroma.Style {
+	style, err := styles.Get("onedark").Builder().Add(chroma.Background, "#abb2bf bg:#161c24").Build()
+	if err != nil {
+		return styles.Fallback
+	}
+	return style
+}()
+
+// codeClasses are the classes of the pre elements holding code and line numbers
+const codeClasses = "overflow-x-auto my-0 p-4 text-sm leading-relaxed"
+
+// codeWrapper wraps highlighted code in pre and code elements, copy buttons copy the element marked with data-code
+// so line numbers are written to a pre of their own
+type codeWrapper struct{}
+
+func (codeWrapper) Start(code bool, styleAttr string) string {
+	if code {
+		return fmt.Sprintf(`<pre class="%s"%s><code data-code>`, codeClasses, styleAttr)
+	}
+	return fmt.Sprintf(`<pre class="%s"%s aria-hidden="true">`, codeClasses, styleAttr)
+}
+
+func (codeWrapper) End(code bool) string {
+	if code {
+		return "</code></pre>"
+	}
+	return "</pre>"
+}
+
+// highlight renders content highlighted at render time with inline styles so it's styled without any stylesheet or
+// script e.g. in feeds, language is a lexer name, alias or file extension, unknown languages are rendered as plain text
+func highlight(content string, language string, options CodeOptions) templ.Component {
+	return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		lexer := lexers.Get(language)
+		if lexer == nil {
+			lexer = lexers.Fallback
+		}
+
+		iterator, err := chroma.Coalesce(lexer).Tokenise(nil, content)
+		if err != nil {
+			// unhighlighted code is better than no code
+			_, err := fmt.Fprintf(w, `<pre class="%s"><code data-code>%s</code></pre>`, codeClasses, html.EscapeString(content))
+			return err
+		}
+
+		formatter := chromahtml.New(
+			chromahtml.WithClasses(false),
+			chromahtml.TabWidth(4),
+			chromahtml.WithLineNumbers(options.LineNumbers),
+			chromahtml.LineNumbersInTable(true),
+			chromahtml.HighlightLines(options.HighlightLines),
+			chromahtml.WithPreWrapper(codeWrapper{}),
+		)
+		return formatter.Format(w, codeStyle, iterator)
+	})
+}
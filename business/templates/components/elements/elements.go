@@ -1,9 +1,76 @@
 package elements
 
-type P struct {
-	Content string
-	Bold    bool
-	Italic  bool
-	Code    bool
-	Link    *string
+import "strings"
+
+// Mention kinds a Text can refer to
+const (
+	MentionUser = "user"
+	MentionPage = "page"
+	MentionDate = "date"
+)
+
+// Text is a span of rich text, its annotations compose e.g. a bold italic link
+type Text struct {
+	Content       string
+	Bold          bool
+	Italic        bool
+	Strikethrough bool
+	Underline     bool
+	Code          bool
+	// Color is a notion color name like "red" or "blue_background", empty or "default" means no color
+	Color string
+	Link  *string
+	// Equation reports that Content is a TeX expression rendered inline
+	Equation bool
+	// Mention is the kind of entity the span refers to if any, one of MentionUser, MentionPage or MentionDate
+	Mention string
+	// DateTime is the machine readable date of a MentionDate
+	DateTime string
+}
+
+// RichText is a sequence of Text spans forming a single piece of text like a paragraph or a heading
+type RichText []Text
+
+// Plain creates a RichText of a single span without annotations
+func Plain(content string) RichText {
+	if content == "" {
+		return nil
+	}
+	return RichText{{Content: content}}
+}
+
+// String returns the text content without annotations
+func (rt RichText) String() string {
+	var s strings.Builder
+	for _, t := range rt {
+		s.WriteString(t.Content)
+	}
+	return s.String()
+}
+
+// colorClasses maps notion colors to the classes used to render them
+var colorClasses = map[string]string{
+	"gray":              "text-gray-400",
+	"brown":             "text-amber-700",
+	"orange":            "text-orange-400",
+	"yellow":            "text-yellow-400",
+	"green":             "text-green-400",
+	"blue":              "text-blue-400",
+	"purple":            "text-purple-400",
+	"pink":              "text-pink-400",
+	"red":               "text-red-400",
+	"gray_background":   "bg-gray-700/50",
+	"brown_background":  "bg-amber-900/50",
+	"orange_background": "bg-orange-900/50",
+	"yellow_background": "bg-yellow-900/50",
+	"green_background":  "bg-green-900/50",
+	"blue_background":   "bg-blue-900/50",
+	"purple_background": "bg-purple-900/50",
+	"pink_background":   "bg-pink-900/50",
+	"red_background":    "bg-red-900/50",
+}
+
+// colorClass returns the class of a notion color, empty for the default color
+func colorClass(color string) string {
+	return colorClasses[color]
 }
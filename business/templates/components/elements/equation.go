@@ -0,0 +1,26 @@
+package elements
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+
+	"github.com/a-h/templ"
+	"github.com/so-heil/goblog/foundation/mathml"
+)
+
+// math renders the TeX expression as MathML at render time so it's shown without scripts e.g. in feeds,
+// expressions the converter doesn't support are shown as TeX source
+func math(expression string, display bool) templ.Component {
+	return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		markup, err := mathml.Render(expression, display)
+		if err != nil {
+			_, err := fmt.Fprintf(w, "<code>%s</code>", html.EscapeString(expression))
+			return err
+		}
+
+		_, err = io.WriteString(w, markup)
+		return err
+	})
+}
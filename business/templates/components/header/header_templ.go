@@ -11,6 +11,7 @@ import "io"
 import "bytes"
 
 import (
+	"github.com/so-heil/goblog/business/assets"
 	"github.com/so-heil/goblog/business/templates/components/breadcrumb"
 )
 
@@ -27,7 +28,15 @@ func Header(links []breadcrumb.Link) templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("<header class=\"flex md:px-12 px-6 items-center w-full py-4 md:py-8 sticky top-0 backdrop-blur z-40 text-[13px] md:text-base space-x-4 md:space-x-8\"><a class=\"opacity-80 hover:opacity-100\" href=\"/\"><img src=\"/static/images/pilot-bust.svg\" class=\"w-12 h-12 md:w-16 md:h-10 opacity-70\"></a><div class=\"flex flex-col-reverse md:flex-row md:items-center flex-1 md:justify-between\"><div class=\"flex items-center text-white\"><div>")
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("<header class=\"flex md:px-12 px-6 items-center w-full py-4 md:py-8 sticky top-0 backdrop-blur z-40 text-[13px] md:text-base space-x-4 md:space-x-8\"><a class=\"opacity-80 hover:opacity-100\" href=\"/\"><img src=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(assets.URL("/static/images/pilot-bust.svg")))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("\" class=\"w-12 h-12 md:w-16 md:h-10 opacity-70\"></a><div class=\"flex flex-col-reverse md:flex-row md:items-center flex-1 md:justify-between\"><div class=\"flex items-center text-white\"><div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -65,7 +74,15 @@ func Header(links []breadcrumb.Link) templ.Component {
 				}
 			}
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("</div></div><a href=\"https://github.com/so-heil\" class=\"opacity-80 transition-all hover:opacity-100\"><img src=\"/static/images/github-mark-white.svg\" class=\"w-5 h-5 md:w-6 md:h-6 transition-all\"></a></header>")
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("</div></div><a href=\"https://github.com/so-heil\" class=\"opacity-80 transition-all hover:opacity-100\"><img src=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(assets.URL("/static/images/github-mark-white.svg")))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("\" class=\"w-5 h-5 md:w-6 md:h-6 transition-all\"></a></header>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -1,5 +1,7 @@
 package header
 
+import "github.com/so-heil/goblog/business/assets"
+
 type Nav struct {
 	Title string
 	Href  string
@@ -16,6 +18,6 @@ var navs = []Nav{
 	},
 	{
 		Title: "CV",
-		Href:  "/static/cv.pdf",
+		Href:  assets.URL("/static/cv.pdf"),
 	},
 }
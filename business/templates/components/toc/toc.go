@@ -1,7 +1,62 @@
 package toc
 
-import "strings"
+import (
+	"strconv"
 
-func ElementID(title string) string {
-	return strings.Replace(title, " ", "-", -1)
+	"github.com/so-heil/goblog/foundation/slug"
+)
+
+// Heading is a heading of a page the table of contents links to
+type Heading struct {
+	// ID is the unique anchor of the heading in its page
+	ID    string
+	Title string
+	// Level is 1, 2 or 3 for h1, h2 and h3 headings
+	Level int
+}
+
+// Entry is a heading of the table of contents with the lower level headings following it
+type Entry struct {
+	Heading
+	Children []Entry
+}
+
+// Nest builds the table of contents of headings in page order, every heading is nested under the closest preceding
+// heading of a higher level
+func Nest(headings []Heading) []Entry {
+	var entries []Entry
+	for i := 0; i < len(headings); {
+		end := i + 1
+		for end < len(headings) && headings[end].Level > headings[i].Level {
+			end++
+		}
+		entries = append(entries, Entry{Heading: headings[i], Children: Nest(headings[i+1 : end])})
+		i = end
+	}
+	return entries
+}
+
+// Slugger creates the unique anchors of the headings of a single page, the same headings always get the same anchors
+type Slugger struct {
+	seen map[string]bool
+}
+
+// NewSlugger creates a Slugger for a page, a single Slugger should slug every heading of the page to keep anchors unique
+func NewSlugger() *Slugger {
+	return &Slugger{seen: make(map[string]bool)}
+}
+
+// Slug returns the slug.Make slug of title, titles slugged before get a numbered suffix e.g. setup-1
+func (s *Slugger) Slug(title string) string {
+	base := slug.Make(title)
+	if base == "" {
+		base = "section"
+	}
+
+	slug := base
+	for i := 1; s.seen[slug]; i++ {
+		slug = base + "-" + strconv.Itoa(i)
+	}
+	s.seen[slug] = true
+	return slug
 }
@@ -17,7 +17,7 @@ import (
 	"strings"
 )
 
-func ArticlePage(links []breadcrumb.Link, article Article, content []templ.Component, headings []string) templ.Component {
+func ArticlePage(links []breadcrumb.Link, article Article, content []templ.Component, entries []toc.Entry) templ.Component {
 	return templ.ComponentFunc(func(ctx context.Context, templ_7745c5c3_W io.Writer) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templ_7745c5c3_W.(*bytes.Buffer)
 		if !templ_7745c5c3_IsBuffer {
@@ -40,7 +40,7 @@ func ArticlePage(links []breadcrumb.Link, article Article, content []templ.Compo
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = toc.TOC(headings).Render(ctx, templ_7745c5c3_Buffer)
+			templ_7745c5c3_Err = toc.TOC(entries).Render(ctx, templ_7745c5c3_Buffer)
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -62,11 +62,19 @@ func ArticlePage(links []breadcrumb.Link, article Article, content []templ.Compo
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("</div><div class=\"mt-3\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = tagLinks(article.Tags).Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("</div><div class=\"w-full lg:hidden mt-28\">")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = toc.TOC(headings).Render(ctx, templ_7745c5c3_Buffer)
+			templ_7745c5c3_Err = toc.TOC(entries).Render(ctx, templ_7745c5c3_Buffer)
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -89,16 +97,16 @@ func ArticlePage(links []breadcrumb.Link, article Article, content []templ.Compo
                          entries.forEach(entry => {
                              const id = entry.target.getAttribute('id');
                              if (entry.intersectionRatio > 0) {
-                                 document.querySelector(` + "`" + `#toc > ul > li > a[href="#${id}"]` + "`" + `).parentElement.classList.add('active-toc-item');
+                                 document.querySelectorAll(` + "`" + `#toc a[href="#${id}"]` + "`" + `).forEach(a => a.classList.add('active-toc-item'));
                              } else {
-                                 document.querySelector(` + "`" + `#toc > ul > li > a[href="#${id}"]` + "`" + `).parentElement.classList.remove('active-toc-item');
+                                 document.querySelectorAll(` + "`" + `#toc a[href="#${id}"]` + "`" + `).forEach(a => a.classList.remove('active-toc-item'));
                              }
                          });
                     });
 
-                    // Track all sections that have an ` + "`" + `id` + "`" + ` applied
-                    document.querySelectorAll('section[id]').forEach((section) => {
-                         observer.observe(section);
+                    // Track all headings listed in the table of contents
+                    document.querySelectorAll('article :is(h1, h2, h3)[id]').forEach((heading) => {
+                         observer.observe(heading);
                     });
             `
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ_7745c5c3_Var5)
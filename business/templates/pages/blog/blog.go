@@ -7,4 +7,35 @@ type Article struct {
 	Excerpt   string
 	WrittenAt time.Time
 	Slug      string
+	Tags      []Tag
+}
+
+// Tag is a tag articles are grouped by, Href is its listing page
+type Tag struct {
+	Name string
+	Href string
+	// Count is the number of articles with the tag
+	Count int
+}
+
+// tagSizes are the text sizes of tags in the tag cloud from the least to the most used
+var tagSizes = []string{"text-base", "text-xl", "text-2xl", "text-3xl", "text-4xl"}
+
+// tagSize returns the text size of tag in a cloud where the most used tag has maxCount articles
+func tagSize(tag Tag, maxCount int) string {
+	if maxCount <= 1 {
+		return tagSizes[0]
+	}
+	return tagSizes[(tag.Count-1)*(len(tagSizes)-1)/(maxCount-1)]
+}
+
+// maxCount returns the article count of the most used tag
+func maxCount(tags []Tag) int {
+	var m int
+	for _, tag := range tags {
+		if tag.Count > m {
+			m = tag.Count
+		}
+	}
+	return m
 }
@@ -0,0 +1,77 @@
+package frontend
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/so-heil/goblog/business/assets"
+)
+
+// CacheControl is the Cache-Control header value of each kind of response, an empty value sends no header
+type CacheControl struct {
+	// Pages are HTML pages, they change on every update so they are revalidated by their ETag
+	Pages string
+	// Documents are feeds, sitemap and robots
+	Documents string
+	// Static are embedded assets under /static/
+	Static string
+	// Fingerprinted are embedded assets under their fingerprinted URLs, they change whenever their content does
+	Fingerprinted string
+	// Files are self-hosted files, their names are content hashes so they never change
+	Files string
+}
+
+// DefaultCacheControl revalidates pages on every request and caches the rest for as long as they may change
+var DefaultCacheControl = CacheControl{
+	Pages:         "public, no-cache",
+	Documents:     "public, max-age=3600",
+	Static:        "public, max-age=86400",
+	Fingerprinted: "public, max-age=31536000, immutable",
+	Files:         "public, max-age=31536000, immutable",
+}
+
+// etag is the strong entity tag of content, a hash of it
+func etag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// serveContent writes content with its ETag and Last-Modified validators and cacheControl, conditional requests
+// with If-None-Match or If-Modified-Since matching content are answered with 304 Not Modified, a zero modTime
+// sends no Last-Modified
+func serveContent(w http.ResponseWriter, r *http.Request, content []byte, contentType string, modTime time.Time, cacheControl string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", etag(content))
+	if cacheControl != "" {
+		w.Header().Set("Cache-Control", cacheControl)
+	}
+	http.ServeContent(w, r, "", modTime, bytes.NewReader(content))
+}
+
+// static serves embedded assets, fingerprinted URLs are cached by their own policy as they never change,
+// embedded files have no modification time so they are revalidated by their ETag
+func (frontend *Frontend) static(w http.ResponseWriter, r *http.Request) {
+	cacheControl := frontend.cacheControl.Static
+	if assets.IsFingerprinted(r.URL.Path) {
+		cacheControl = frontend.cacheControl.Fingerprinted
+	}
+	// the file server answers conditional requests matching the ETag already set on the response
+	if etag, ok := assets.ETag(r.URL.Path); ok {
+		w.Header().Set("ETag", etag)
+	}
+	withCacheControl(frontend.assetFiles, cacheControl).ServeHTTP(w, r)
+}
+
+// withCacheControl sets the Cache-Control header of every response of h to cacheControl
+func withCacheControl(h http.Handler, cacheControl string) http.Handler {
+	if cacheControl == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", cacheControl)
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,96 @@
+package frontend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/so-heil/goblog/business/pages"
+)
+
+// acceptedEncodings returns the q-value of every content encoding listed in the Accept-Encoding of r,
+// encodings listed without one have a q-value of 1 and a zero q-value refuses the encoding
+func acceptedEncodings(r *http.Request) map[string]float64 {
+	accepted := make(map[string]float64)
+	for _, header := range r.Header.Values("Accept-Encoding") {
+		for _, part := range strings.Split(header, ",") {
+			coding, params, _ := strings.Cut(part, ";")
+			coding = strings.ToLower(strings.TrimSpace(coding))
+			if coding == "" {
+				continue
+			}
+
+			q := 1.0
+			for _, param := range strings.Split(params, ";") {
+				v, ok := strings.CutPrefix(strings.TrimSpace(param), "q=")
+				if !ok {
+					continue
+				}
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil && parsed >= 0 && parsed <= 1 {
+					q = parsed
+				}
+			}
+			accepted[coding] = q
+		}
+	}
+	return accepted
+}
+
+// negotiateEncoding returns the one of pages.Encodings r accepts with the highest q-value, ties are broken by the order
+// of pages.Encodings, encodings r doesn't list are accepted with the q-value of *, it's empty if r accepts none
+func negotiateEncoding(r *http.Request) string {
+	accepted := acceptedEncodings(r)
+	var negotiated string
+	var best float64
+	for _, encoding := range pages.Encodings {
+		q, listed := accepted[encoding]
+		if !listed {
+			q = accepted["*"]
+		}
+		if q > best {
+			negotiated, best = encoding, q
+		}
+	}
+	return negotiated
+}
+
+// loadNegotiated loads the stored page with id in the encoding negotiated with r, falling back to the page itself
+// when r accepts no stored encoding or the page has no variants yet, the returned encoding is empty if it isn't encoded
+func (frontend *Frontend) loadNegotiated(ctx context.Context, r *http.Request, id string) ([]byte, string, error) {
+	if encoding := negotiateEncoding(r); encoding != "" {
+		content, err := frontend.store.LoadEncoded(ctx, id, encoding)
+		if err == nil {
+			return content, encoding, nil
+		}
+		if !errors.Is(err, pages.ErrArticleNotFound) {
+			return nil, "", fmt.Errorf("load %s encoded page %s: %w", encoding, id, err)
+		}
+	}
+
+	content, err := frontend.store.Load(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	return content, "", nil
+}
+
+// serveNegotiated serves the stored page with id in the encoding negotiated with r as contentType, see serveContent
+func (frontend *Frontend) serveNegotiated(w http.ResponseWriter, r *http.Request, id string, contentType string, cacheControl string) error {
+	content, encoding, err := frontend.loadNegotiated(r.Context(), r, id)
+	if err != nil {
+		return err
+	}
+
+	// the response differs by Accept-Encoding even when the page is served as is
+	w.Header().Add("Vary", "Accept-Encoding")
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+
+	version, _ := frontend.store.Version(id)
+	serveContent(w, r, content, contentType, version, cacheControl)
+	return nil
+}
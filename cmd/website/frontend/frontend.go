@@ -1,12 +1,16 @@
 package frontend
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"mime"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/so-heil/goblog/business/assets"
 	"github.com/so-heil/goblog/business/pages"
@@ -15,36 +19,98 @@ import (
 type Frontend struct {
 	store      pages.Store
 	assetFiles *assets.Assets
+	provider   pages.Provider
+	// previewSecret authorizes previews, previews are disabled when it's empty
+	previewSecret string
+	revalidate    func()
+	// revalidateSecret authorizes revalidation, revalidation is disabled when it's empty
+	revalidateSecret string
+	cacheControl     CacheControl
 }
 
-func New(storer pages.Store, assetFiles *assets.Assets) *Frontend {
-	return &Frontend{store: storer, assetFiles: assetFiles}
+// New creates a Frontend serving pages from storer, previews and revalidation are enabled by opts
+func New(storer pages.Store, assetFiles *assets.Assets, opts ...Option) *Frontend {
+	frontend := &Frontend{store: storer, assetFiles: assetFiles, cacheControl: DefaultCacheControl}
+	for _, opt := range opts {
+		opt(frontend)
+	}
+	return frontend
 }
 
-// Routes registers paths to mux
+// Routes registers paths to mux, every path but revalidation only serves GET and HEAD requests
 func (frontend *Frontend) Routes(mux *http.ServeMux) {
-	mux.Handle("/static/", frontend.assetFiles)
-	mux.HandleFunc("/about", frontend.aboutPage)
-	mux.HandleFunc("/blog", frontend.blogPage)
-	mux.HandleFunc("/blog/", frontend.articlePage)
-	mux.HandleFunc("/", frontend.root)
+	mux.Handle("/static/", readOnly(http.HandlerFunc(frontend.static)))
+	mux.Handle(pages.FilesPath, readOnly(http.HandlerFunc(frontend.file)))
+	mux.Handle(pages.RSSFeedPath, readOnly(frontend.document(pages.RSSFeedID, "application/rss+xml; charset=utf-8")))
+	mux.Handle(pages.AtomFeedPath, readOnly(frontend.document(pages.AtomFeedID, "application/atom+xml; charset=utf-8")))
+	mux.Handle(pages.JSONFeedPath, readOnly(frontend.document(pages.JSONFeedID, "application/feed+json; charset=utf-8")))
+	mux.Handle(pages.SitemapPath, readOnly(frontend.document(pages.SitemapID, "application/xml; charset=utf-8")))
+	mux.Handle(pages.RobotsPath, readOnly(frontend.document(pages.RobotsID, "text/plain; charset=utf-8")))
+	mux.Handle(PreviewPath, readOnly(http.HandlerFunc(frontend.preview)))
+	mux.HandleFunc(RevalidatePath, frontend.revalidation)
+	mux.Handle("/about", readOnly(http.HandlerFunc(frontend.aboutPage)))
+	mux.Handle("/blog", readOnly(http.HandlerFunc(frontend.blogPage)))
+	mux.Handle("/blog/", readOnly(http.HandlerFunc(frontend.articlePage)))
+	mux.Handle("/blog/tags", readOnly(http.HandlerFunc(frontend.tagsPage)))
+	mux.Handle("/blog/tags/", readOnly(http.HandlerFunc(frontend.tagPage)))
+	mux.Handle("/", readOnly(http.HandlerFunc(frontend.root)))
+}
+
+// readOnly responds 405 Method Not Allowed to requests other than GET and HEAD
+func readOnly(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			methodNotAllowed(w, http.MethodGet, http.MethodHead)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// methodNotAllowed responds 405 Method Not Allowed listing the allowed methods
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 }
 
 func (frontend *Frontend) blogPage(w http.ResponseWriter, r *http.Request) {
-	if err := frontend.handlePage(pages.BlogPageID, w); err != nil {
-		internalError(err, w, r)
+	if err := frontend.handlePage(w, r, pages.BlogPageID, http.StatusOK); err != nil {
+		frontend.internalError(err, w, r)
 	}
 }
 
 func (frontend *Frontend) aboutPage(w http.ResponseWriter, r *http.Request) {
-	if err := frontend.handlePage(pages.AboutPageID, w); err != nil {
-		internalError(err, w, r)
+	if err := frontend.handlePage(w, r, pages.AboutPageID, http.StatusOK); err != nil {
+		frontend.internalError(err, w, r)
 	}
 }
 
+func (frontend *Frontend) tagsPage(w http.ResponseWriter, r *http.Request) {
+	if err := frontend.handlePage(w, r, pages.TagsPageID, http.StatusOK); err != nil {
+		frontend.internalError(err, w, r)
+	}
+}
+
+func (frontend *Frontend) tagPage(w http.ResponseWriter, r *http.Request) {
+	slug := path.Base(r.URL.Path)
+	if slug == "tags" {
+		frontend.tagsPage(w, r)
+		return
+	}
+
+	if err := frontend.handlePage(w, r, pages.TagPageID(slug), http.StatusOK); err != nil {
+		if errors.Is(err, pages.ErrArticleNotFound) {
+			frontend.notFound(w, r)
+			return
+		}
+		frontend.internalError(err, w, r)
+	}
+}
+
+// notFound serves the not found page with 404 Not Found status
 func (frontend *Frontend) notFound(w http.ResponseWriter, r *http.Request) {
-	if err := frontend.handlePage(pages.NotFoundPageID, w); err != nil {
-		internalError(err, w, r)
+	if err := frontend.handlePage(w, r, pages.NotFoundPageID, http.StatusNotFound); err != nil {
+		frontend.internalError(err, w, r)
 	}
 }
 
@@ -55,13 +121,42 @@ func (frontend *Frontend) articlePage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := frontend.handlePage(slug, w); err != nil {
+	if err := frontend.handlePage(w, r, slug, http.StatusOK); err != nil {
 		if errors.Is(err, pages.ErrArticleNotFound) {
 			frontend.notFound(w, r)
 			return
 		}
-		internalError(err, w, r)
+		frontend.internalError(err, w, r)
+	}
+}
+
+// document serves the stored non HTML page with id, e.g. feeds, as contentType
+func (frontend *Frontend) document(id string, contentType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := frontend.serveNegotiated(w, r, id, contentType, frontend.cacheControl.Documents); err != nil {
+			frontend.internalError(err, w, r)
+		}
+	}
+}
+
+// file serves files self-hosted in store, their names are content hashes so they never change
+func (frontend *Frontend) file(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, pages.FilesPath)
+	content, err := frontend.store.LoadFile(r.Context(), name)
+	if err != nil {
+		if errors.Is(err, pages.ErrFileNotFound) {
+			frontend.notFound(w, r)
+			return
+		}
+		frontend.internalError(err, w, r)
+		return
 	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(content)
+	}
+	serveContent(w, r, content, contentType, time.Time{}, frontend.cacheControl.Files)
 }
 
 func (frontend *Frontend) root(w http.ResponseWriter, r *http.Request) {
@@ -73,13 +168,25 @@ func (frontend *Frontend) root(w http.ResponseWriter, r *http.Request) {
 	frontend.notFound(w, r)
 }
 
-func (frontend *Frontend) handlePage(id string, w http.ResponseWriter) error {
-	page, err := frontend.store.Load(id)
+// handlePage serves the stored page with id with status, pages served with 200 OK are cacheable and compressed
+// when the client accepts it
+func (frontend *Frontend) handlePage(w http.ResponseWriter, r *http.Request, id string, status int) error {
+	if status == http.StatusOK {
+		if err := frontend.serveNegotiated(w, r, id, "text/html", frontend.cacheControl.Pages); err != nil {
+			return fmt.Errorf("handlePage: serve page %s: %w", id, err)
+		}
+		return nil
+	}
+
+	page, err := frontend.store.Load(r.Context(), id)
 	if err != nil {
-		return fmt.Errorf("handlePage: load page %s: %s", id, err)
+		return fmt.Errorf("handlePage: load page %s: %w", id, err)
 	}
 
+	// error responses are never stored by caches
 	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
 	if _, err := w.Write(page); err != nil {
 		return fmt.Errorf("handlePage: write reponse: %w", err)
 	}
@@ -87,14 +194,18 @@ func (frontend *Frontend) handlePage(id string, w http.ResponseWriter) error {
 	return nil
 }
 
-func internalError(err error, w http.ResponseWriter, r *http.Request) {
+// internalError logs err and serves the server error page with 500 Internal Server Error status,
+// falling back to plain text if the page itself can't be served
+func (frontend *Frontend) internalError(err error, w http.ResponseWriter, r *http.Request) {
 	fmt.Printf("ERROR: internal server error: page: %s: %s\n", r.URL.String(), err)
-	w.WriteHeader(http.StatusInternalServerError)
-	w.Write([]byte("something went wrong"))
+	if err := frontend.handlePage(w, r, pages.ServerErrorPageID, http.StatusInternalServerError); err != nil {
+		fmt.Printf("ERROR: serve server error page: %s\n", err)
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+	}
 }
 
 // SSG is a Static Site Generator that runs a static build of the website putting the resulting static files in dir
-func (frontend *Frontend) SSG(dir string, perm os.FileMode) error {
+func (frontend *Frontend) SSG(ctx context.Context, dir string, perm os.FileMode) error {
 	// copy asset files to static directory
 	if err := frontend.assetFiles.RecursiveCopy(dir, perm); err != nil {
 		return fmt.Errorf("recursive copy assets: %w", err)
@@ -109,25 +220,52 @@ func (frontend *Frontend) SSG(dir string, perm os.FileMode) error {
 		{pages.AboutPageID, "index.html"},
 		{pages.AboutPageID, "about.html"},
 		{pages.BlogPageID, "blog/index.html"},
+		{pages.TagsPageID, "blog/tags/index.html"},
+		{pages.NotFoundPageID, "404.html"},
+		{pages.ServerErrorPageID, "500.html"},
+		{pages.RSSFeedID, pages.RSSFeedPath},
+		{pages.AtomFeedID, pages.AtomFeedPath},
+		{pages.JSONFeedID, pages.JSONFeedPath},
+		{pages.SitemapID, pages.SitemapPath},
+		{pages.RobotsID, pages.RobotsPath},
+	}
+	special := make(map[string]bool)
+	for _, p := range staticPages {
+		special[p.id] = true
 	}
 
-	// add article pages to static pages
-	for slug := range frontend.store.Versions() {
-		if slug != pages.AboutPageID && slug != pages.BlogPageID {
-			staticPages = append(staticPages, staticPage{slug, fmt.Sprintf("blog/%s.html", slug)})
+	// add article and tag pages to static pages
+	for id := range frontend.store.Versions() {
+		if tag, ok := strings.CutPrefix(id, pages.TagPagePrefix); ok {
+			staticPages = append(staticPages, staticPage{id, fmt.Sprintf("blog/tags/%s.html", tag)})
+			continue
+		}
+		if !special[id] {
+			staticPages = append(staticPages, staticPage{id, fmt.Sprintf("blog/%s.html", id)})
 		}
 	}
 
 	for _, p := range staticPages {
-		if err := frontend.putStaticPage(p.id, filepath.Join(dir, p.relativePath), perm); err != nil {
+		if err := frontend.putStaticPage(ctx, p.id, filepath.Join(dir, p.relativePath), perm); err != nil {
 			return fmt.Errorf("put static page %s: %w", p.id, err)
 		}
 	}
 
+	// copy self-hosted files
+	files, err := frontend.store.Files()
+	if err != nil {
+		return fmt.Errorf("list files: %w", err)
+	}
+	for _, name := range files {
+		if err := frontend.putStaticFile(ctx, name, filepath.Join(dir, pages.FilesPath, name), perm); err != nil {
+			return fmt.Errorf("put static file %s: %w", name, err)
+		}
+	}
+
 	return nil
 }
 
-func (frontend *Frontend) putStaticPage(id string, path string, perm os.FileMode) error {
+func (frontend *Frontend) putStaticPage(ctx context.Context, id string, path string, perm os.FileMode) error {
 	// create parent directory if not exists
 	if err := os.MkdirAll(filepath.Dir(path), perm); err != nil {
 		return fmt.Errorf("make path dir: %w", err)
@@ -139,7 +277,7 @@ func (frontend *Frontend) putStaticPage(id string, path string, perm os.FileMode
 	}
 	defer f.Close()
 
-	page, err := frontend.store.Load(id)
+	page, err := frontend.store.Load(ctx, id)
 	if err != nil {
 		return fmt.Errorf("load page for static generation: %w", err)
 	}
@@ -148,5 +286,43 @@ func (frontend *Frontend) putStaticPage(id string, path string, perm os.FileMode
 		return fmt.Errorf("write static page in target file: %w", err)
 	}
 
+	// pre-compressed siblings, e.g. index.html.br, are served by web servers supporting them
+	for _, encoding := range pages.Encodings {
+		encoded, err := frontend.store.LoadEncoded(ctx, id, encoding)
+		if err != nil {
+			if errors.Is(err, pages.ErrArticleNotFound) {
+				continue
+			}
+			return fmt.Errorf("load %s encoded page for static generation: %w", encoding, err)
+		}
+
+		if err := os.WriteFile(path+encodingExtensions[encoding], encoded, perm); err != nil {
+			return fmt.Errorf("write %s encoded static page: %w", encoding, err)
+		}
+	}
+
+	return nil
+}
+
+// encodingExtensions are the file extensions of pre-compressed static pages by their content encoding
+var encodingExtensions = map[string]string{
+	pages.EncodingBrotli: ".br",
+	pages.EncodingGzip:   ".gz",
+}
+
+func (frontend *Frontend) putStaticFile(ctx context.Context, name string, path string, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), perm); err != nil {
+		return fmt.Errorf("make path dir: %w", err)
+	}
+
+	content, err := frontend.store.LoadFile(ctx, name)
+	if err != nil {
+		return fmt.Errorf("load file for static generation: %w", err)
+	}
+
+	if err := os.WriteFile(path, content, perm); err != nil {
+		return fmt.Errorf("write static file in target file: %w", err)
+	}
+
 	return nil
 }
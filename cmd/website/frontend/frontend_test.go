@@ -0,0 +1,434 @@
+package frontend_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/dgraph-io/badger/v4"
+	"github.com/so-heil/goblog/business/articles"
+	"github.com/so-heil/goblog/business/assets"
+	"github.com/so-heil/goblog/business/pages"
+	"github.com/so-heil/goblog/business/repository"
+	"github.com/so-heil/goblog/cmd/website/frontend"
+)
+
+var version = time.Date(2023, 11, 20, 10, 0, 0, 0, time.UTC)
+
+// newStore returns a repository backed by an in-memory badger db holding every page served by the frontend,
+// pages are stored with fake variants prefixed by their encoding
+func newStore(t *testing.T) *repository.Repository {
+	ctx := context.Background()
+
+	options := badger.DefaultOptions("")
+	options.InMemory = true
+	options.Logger = nil
+	db, err := badger.Open(options)
+	if err != nil {
+		t.Fatalf("open in-memory badger db: %s", err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Fatalf("close db: %s", err)
+		}
+	})
+
+	s, err := repository.New(db)
+	if err != nil {
+		t.Fatalf("new repository: %s", err)
+	}
+
+	ids := []string{
+		pages.AboutPageID, pages.BlogPageID, pages.TagsPageID, pages.NotFoundPageID, pages.ServerErrorPageID,
+		pages.RSSFeedID, pages.AtomFeedID, pages.JSONFeedID, pages.SitemapID, pages.RobotsID, "first-article",
+	}
+	for _, id := range ids {
+		encoded := map[string][]byte{
+			pages.EncodingBrotli: []byte("br:" + id),
+			pages.EncodingGzip:   []byte("gzip:" + id),
+		}
+		if err := s.Store(ctx, id, []byte(id), encoded, version); err != nil {
+			t.Fatalf("store page %s: %s", id, err)
+		}
+	}
+	if err := s.StoreFile(ctx, "0123456789abcdef.png", []byte("image")); err != nil {
+		t.Fatalf("store file: %s", err)
+	}
+
+	return s
+}
+
+// newServer returns the frontend routes serving the pages of s
+func newServer(s pages.Store) http.Handler {
+	mux := http.NewServeMux()
+	frontend.New(s, assets.New()).Routes(mux)
+	return mux
+}
+
+func get(h http.Handler, url string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, url, nil)
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestEncodingNegotiation(t *testing.T) {
+	h := newServer(newStore(t))
+
+	tests := []struct {
+		accept   string
+		encoding string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"gzip, br", "br"},
+		{"br;q=0.1, gzip", "gzip"},
+		{"br;q=0.5, gzip;q=0.5", "br"},
+		{"br;q=0, gzip;q=0", ""},
+		{"br;q=0", ""},
+		{"*", "br"},
+		{"br;q=0, *", "gzip"},
+		{"gzip;q=0.8, *;q=0.9", "br"},
+		{"GZIP; q=1.0", "gzip"},
+	}
+	for _, tt := range tests {
+		w := get(h, "/blog", http.Header{"Accept-Encoding": {tt.accept}})
+		if w.Code != http.StatusOK {
+			t.Fatalf("Accept-Encoding %q: should serve the page, got status: %d", tt.accept, w.Code)
+		}
+
+		if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+			t.Errorf("Accept-Encoding %q: should be served with encoding %q, got: %q", tt.accept, tt.encoding, got)
+		}
+		want := pages.BlogPageID
+		if tt.encoding != "" {
+			want = tt.encoding + ":" + want
+		}
+		if w.Body.String() != want {
+			t.Errorf("Accept-Encoding %q: should serve the %q variant, got: %q", tt.accept, tt.encoding, w.Body.String())
+		}
+		if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+			t.Errorf("Accept-Encoding %q: responses should vary by Accept-Encoding, got: %q", tt.accept, got)
+		}
+	}
+
+	w := get(h, pages.RSSFeedPath, http.Header{"Accept-Encoding": {"gzip"}})
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("documents should be served compressed, got headers: %v", w.Header())
+	}
+}
+
+func TestSSG(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	if err := frontend.New(newStore(t), assets.New()).SSG(ctx, dir, 0777); err != nil {
+		t.Fatalf("static build: %s", err)
+	}
+
+	for name, want := range map[string]string{
+		"index.html":                 pages.AboutPageID,
+		"index.html.br":              "br:" + pages.AboutPageID,
+		"index.html.gz":              "gzip:" + pages.AboutPageID,
+		"blog/first-article.html":    "first-article",
+		"blog/first-article.html.br": "br:first-article",
+		"feed.xml.gz":                "gzip:" + pages.RSSFeedID,
+		"files/0123456789abcdef.png": "image",
+	} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("%s should be written: %s", name, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("%s should hold %q, got: %q", name, want, got)
+		}
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	h := newServer(newStore(t))
+
+	w := get(h, "/blog/first-article", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("should serve the article, got status: %d", w.Code)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("pages should have an ETag")
+	}
+	if got := w.Header().Get("Last-Modified"); got != version.Format(http.TimeFormat) {
+		t.Errorf("pages should be last modified at their version, got: %q", got)
+	}
+
+	w = get(h, "/blog/first-article", http.Header{"If-None-Match": {etag}})
+	if w.Code != http.StatusNotModified {
+		t.Errorf("matching If-None-Match should be answered with 304, got: %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Error("304 responses should have no body")
+	}
+
+	w = get(h, "/blog/first-article", http.Header{"If-None-Match": {`"stale"`}})
+	if w.Code != http.StatusOK {
+		t.Errorf("changed pages should be served, got: %d", w.Code)
+	}
+
+	w = get(h, "/blog/first-article", http.Header{"If-Modified-Since": {version.Format(http.TimeFormat)}})
+	if w.Code != http.StatusNotModified {
+		t.Errorf("pages not modified since If-Modified-Since should be answered with 304, got: %d", w.Code)
+	}
+
+	// variants are different representations with their own validators
+	w = get(h, "/blog/first-article", http.Header{"Accept-Encoding": {"br"}, "If-None-Match": {etag}})
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("compressed variant should not match the ETag of the page, got: %d, %s", w.Code, w.Header().Get("ETag"))
+	}
+
+	// embedded assets have no modification time and are revalidated by their ETag only
+	for _, url := range []string{"/static/fonts/fonts.css", assets.URL("/static/fonts/fonts.css")} {
+		w = get(h, url, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: should serve the asset, got status: %d", url, w.Code)
+		}
+		etag := w.Header().Get("ETag")
+		if etag == "" {
+			t.Fatalf("%s: assets should have an ETag", url)
+		}
+
+		w = get(h, url, http.Header{"If-None-Match": {etag}})
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("%s: matching If-None-Match should be answered with 304, got: %d", url, w.Code)
+		}
+		w = get(h, url, http.Header{"If-None-Match": {`"stale"`}})
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: changed assets should be served, got: %d", url, w.Code)
+		}
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	h := newServer(newStore(t))
+	cc := frontend.DefaultCacheControl
+
+	tests := []struct {
+		url          string
+		cacheControl string
+		lastModified bool
+	}{
+		{"/", cc.Pages, true},
+		{"/about", cc.Pages, true},
+		{"/blog", cc.Pages, true},
+		{"/blog/first-article", cc.Pages, true},
+		{"/blog/tags", cc.Pages, true},
+		{pages.RSSFeedPath, cc.Documents, true},
+		{pages.AtomFeedPath, cc.Documents, true},
+		{pages.JSONFeedPath, cc.Documents, true},
+		{pages.SitemapPath, cc.Documents, true},
+		{pages.RobotsPath, cc.Documents, true},
+		// embedded files have no modification time
+		{"/static/fonts/fonts.css", cc.Static, false},
+		{assets.URL("/static/fonts/fonts.css"), cc.Fingerprinted, false},
+		{pages.FilesPath + "0123456789abcdef.png", cc.Files, false},
+	}
+	for _, tt := range tests {
+		w := get(h, tt.url, nil)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: should be served, got status: %d", tt.url, w.Code)
+			continue
+		}
+		if got := w.Header().Get("Cache-Control"); got != tt.cacheControl {
+			t.Errorf("%s: Cache-Control should be %q, got: %q", tt.url, tt.cacheControl, got)
+		}
+		if _, ok := w.Header()["Last-Modified"]; ok != tt.lastModified {
+			t.Errorf("%s: Last-Modified should be sent: %t, got: %q", tt.url, tt.lastModified, w.Header().Get("Last-Modified"))
+		}
+		etag := w.Header().Get("ETag")
+		if etag == "" {
+			continue
+		}
+		if w := get(h, tt.url, http.Header{"If-None-Match": {etag}}); w.Code != http.StatusNotModified {
+			t.Errorf("%s: matching If-None-Match should be answered with 304, got: %d", tt.url, w.Code)
+		}
+	}
+
+	custom := frontend.CacheControl{Pages: "private, no-cache"}
+	mux := http.NewServeMux()
+	frontend.New(newStore(t), assets.New(), frontend.WithCacheControl(custom)).Routes(mux)
+	if got := get(mux, "/blog", nil).Header().Get("Cache-Control"); got != custom.Pages {
+		t.Errorf("configured Cache-Control should be sent, got: %q", got)
+	}
+	if _, ok := get(mux, pages.RobotsPath, nil).Header()["Cache-Control"]; ok {
+		t.Error("empty Cache-Control should send no header")
+	}
+}
+
+func TestStatusCodes(t *testing.T) {
+	ctx := context.Background()
+	s := newStore(t)
+	h := newServer(s)
+
+	for _, url := range []string{"/missing", "/blog/missing-article", "/blog/tags/missing-tag", pages.FilesPath + "missing.png", frontend.PreviewPath + "first-article"} {
+		w := get(h, url, nil)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: should not be found, got status: %d", url, w.Code)
+		}
+		if w.Body.String() != pages.NotFoundPageID {
+			t.Errorf("%s: should serve the not found page, got: %q", url, w.Body.String())
+		}
+		if got := w.Header().Get("Cache-Control"); got != "no-store" {
+			t.Errorf("%s: error responses should not be stored, got Cache-Control: %q", url, got)
+		}
+	}
+
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "/blog", nil))
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s: should not be allowed, got status: %d", method, w.Code)
+		}
+		if got := w.Header().Get("Allow"); got != "GET, HEAD" {
+			t.Errorf("%s: should list the allowed methods, got Allow: %q", method, got)
+		}
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/blog", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("HEAD requests should be allowed, got status: %d", w.Code)
+	}
+
+	mux := http.NewServeMux()
+	frontend.New(s, assets.New(), frontend.WithRevalidation(func() {}, "secret")).Routes(mux)
+	w = get(mux, frontend.RevalidatePath, nil)
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "POST" {
+		t.Errorf("revalidation should only allow POST, got: %d, Allow: %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	// pages that should always be stored are missing
+	if err := s.Delete(ctx, pages.BlogPageID); err != nil {
+		t.Fatalf("delete page: %s", err)
+	}
+	w = get(h, "/blog", nil)
+	if w.Code != http.StatusInternalServerError || w.Body.String() != pages.ServerErrorPageID {
+		t.Errorf("should serve the server error page with 500, got: %d, %q", w.Code, w.Body.String())
+	}
+
+	if err := s.Delete(ctx, pages.ServerErrorPageID); err != nil {
+		t.Fatalf("delete page: %s", err)
+	}
+	w = get(h, "/blog", nil)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("should respond 500 without a server error page, got: %d", w.Code)
+	}
+}
+
+// previewProvider provides a single draft article previewed from the provider
+type previewProvider struct{}
+
+func (previewProvider) Articles(ctx context.Context) ([]articles.Article, error) {
+	return []articles.Article{{ID: "article-1", Title: "Draft Article", Slug: "draft-article", Status: articles.StatusDraft}}, nil
+}
+
+func (previewProvider) Content(ctx context.Context, id string) ([]pages.SectionBlock, error) {
+	return []pages.SectionBlock{{Component: templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		_, err := io.WriteString(w, "<p>draft body</p>")
+		return err
+	})}}, nil
+}
+
+func (previewProvider) AboutPage(ctx context.Context) (pages.About, error) {
+	return pages.About{}, nil
+}
+
+func TestPreview(t *testing.T) {
+	mux := http.NewServeMux()
+	frontend.New(newStore(t), assets.New(), frontend.WithPreview(previewProvider{}, "secret")).Routes(mux)
+
+	url := frontend.PreviewPath + "article-1"
+	tests := []struct {
+		name   string
+		url    string
+		header http.Header
+		status int
+	}{
+		{"preview secret", url, http.Header{"Authorization": {"Bearer secret"}}, http.StatusOK},
+		{"article token", url + "?token=" + frontend.PreviewToken("secret", "article-1"), nil, http.StatusOK},
+		{"token of another article", url + "?token=" + frontend.PreviewToken("secret", "article-2"), nil, http.StatusUnauthorized},
+		{"token signed by another secret", url + "?token=" + frontend.PreviewToken("other", "article-1"), nil, http.StatusUnauthorized},
+		{"wrong secret", url + "?token=" + frontend.PreviewToken("secret", "article-1"), http.Header{"Authorization": {"Bearer wrong"}}, http.StatusUnauthorized},
+		{"no authorization", url, nil, http.StatusUnauthorized},
+		{"unknown article", frontend.PreviewPath + "article-2", http.Header{"Authorization": {"Bearer secret"}}, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := get(mux, tt.url, tt.header)
+		if w.Code != tt.status {
+			t.Errorf("%s: should respond %d, got: %d", tt.name, tt.status, w.Code)
+		}
+		if tt.status == http.StatusOK && !strings.Contains(w.Body.String(), "<p>draft body</p>") {
+			t.Errorf("%s: should render the article from the provider, got: %q", tt.name, w.Body.String())
+		}
+		if got := w.Header().Get("X-Robots-Tag"); !strings.Contains(got, "noindex") {
+			t.Errorf("%s: previews should not be indexed, got X-Robots-Tag: %q", tt.name, got)
+		}
+		if got := w.Header().Get("Cache-Control"); got != "no-store" {
+			t.Errorf("%s: previews should not be stored, got Cache-Control: %q", tt.name, got)
+		}
+	}
+}
+
+func TestRevalidation(t *testing.T) {
+	var triggered int
+	mux := http.NewServeMux()
+	frontend.New(newStore(t), assets.New(), frontend.WithRevalidation(func() { triggered++ }, "secret")).Routes(mux)
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+		triggered     int
+	}{
+		{"no authorization", "", http.StatusUnauthorized, 0},
+		{"wrong secret", "Bearer wrong", http.StatusUnauthorized, 0},
+		{"secret without bearer scheme", "secret", http.StatusUnauthorized, 0},
+		{"secret", "Bearer secret", http.StatusAccepted, 1},
+	}
+	for _, tt := range tests {
+		triggered = 0
+		r := httptest.NewRequest(http.MethodPost, frontend.RevalidatePath, nil)
+		if tt.authorization != "" {
+			r.Header.Set("Authorization", tt.authorization)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: should respond %d, got: %d", tt.name, tt.status, w.Code)
+		}
+		if triggered != tt.triggered {
+			t.Errorf("%s: update should be triggered %d times, got: %d", tt.name, tt.triggered, triggered)
+		}
+	}
+
+	// revalidation is disabled without a secret
+	disabled := http.NewServeMux()
+	frontend.New(newStore(t), assets.New(), frontend.WithRevalidation(func() { triggered++ }, "")).Routes(disabled)
+	triggered = 0
+	r := httptest.NewRequest(http.MethodPost, frontend.RevalidatePath, nil)
+	r.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	disabled.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound || triggered != 0 {
+		t.Errorf("disabled revalidation should not be found, got: %d, triggered: %d", w.Code, triggered)
+	}
+}
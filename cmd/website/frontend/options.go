@@ -0,0 +1,30 @@
+package frontend
+
+import "github.com/so-heil/goblog/business/pages"
+
+// Option configures a Frontend
+type Option func(frontend *Frontend)
+
+// WithPreview enables previewing articles live from provider for requests authorized by secret
+func WithPreview(provider pages.Provider, secret string) Option {
+	return func(frontend *Frontend) {
+		frontend.provider = provider
+		frontend.previewSecret = secret
+	}
+}
+
+// WithCacheControl sets the Cache-Control header of responses, DefaultCacheControl is used otherwise
+func WithCacheControl(cacheControl CacheControl) Option {
+	return func(frontend *Frontend) {
+		frontend.cacheControl = cacheControl
+	}
+}
+
+// WithRevalidation enables the revalidation endpoint calling trigger, e.g. pages.Updater.Trigger,
+// for requests authorized by secret
+func WithRevalidation(trigger func(), secret string) Option {
+	return func(frontend *Frontend) {
+		frontend.revalidate = trigger
+		frontend.revalidateSecret = secret
+	}
+}
@@ -0,0 +1,70 @@
+package frontend
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/so-heil/goblog/business/pages"
+)
+
+// PreviewPath is the path articles are previewed under by their provider id
+const PreviewPath = "/preview/"
+
+// PreviewToken signs id with secret, the token authorizes previewing only that article so it can be shared with reviewers
+func PreviewToken(secret string, id string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// authorized reports whether r may preview the article with id, either by the preview secret as a bearer token
+// or by the article preview token in token query parameter
+func (frontend *Frontend) authorized(r *http.Request, id string) bool {
+	if frontend.previewSecret == "" {
+		return false
+	}
+
+	if bearer, ok := bearerToken(r); ok {
+		return secretEqual(bearer, frontend.previewSecret)
+	}
+
+	return hmac.Equal([]byte(r.URL.Query().Get("token")), []byte(PreviewToken(frontend.previewSecret, id)))
+}
+
+// preview renders the article live from provider bypassing the store, unpublished articles included
+func (frontend *Frontend) preview(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, PreviewPath)
+	if frontend.previewSecret == "" || id == "" {
+		frontend.notFound(w, r)
+		return
+	}
+
+	// previews are never indexed or cached
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if !frontend.authorized(r, id) {
+		http.Error(w, "unauthorized preview", http.StatusUnauthorized)
+		return
+	}
+
+	page, err := pages.Preview(r.Context(), frontend.provider, id)
+	if err != nil {
+		if errors.Is(err, pages.ErrArticleNotFound) {
+			frontend.notFound(w, r)
+			return
+		}
+		frontend.internalError(err, w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := page.Render(r.Context(), w); err != nil {
+		fmt.Printf("ERROR: render preview %s: %s\n", id, err)
+	}
+}
@@ -0,0 +1,43 @@
+package frontend
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// RevalidatePath is the path requesting an immediate store update, e.g. by a notion webhook
+const RevalidatePath = "/_revalidate"
+
+// revalidation triggers a store update for authorized POST requests, the update runs in the background and
+// concurrent requests share a single in-flight update
+func (frontend *Frontend) revalidation(w http.ResponseWriter, r *http.Request) {
+	if frontend.revalidateSecret == "" || frontend.revalidate == nil {
+		frontend.notFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	bearer, ok := bearerToken(r)
+	if !ok || !secretEqual(bearer, frontend.revalidateSecret) {
+		http.Error(w, "unauthorized revalidation", http.StatusUnauthorized)
+		return
+	}
+
+	frontend.revalidate()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// bearerToken returns the bearer token in r authorization header
+func bearerToken(r *http.Request) (string, bool) {
+	return strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// secretEqual compares a token to secret in constant time
+func secretEqual(token string, secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+}
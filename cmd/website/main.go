@@ -1,15 +1,24 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/caarlos0/env/v10"
 	"github.com/dgraph-io/badger/v4"
 	"github.com/so-heil/goblog/business/assets"
+	"github.com/so-heil/goblog/business/markdownprovider"
 	"github.com/so-heil/goblog/business/notionprovider"
 	"github.com/so-heil/goblog/business/pages"
 	"github.com/so-heil/goblog/business/repository"
@@ -24,32 +33,79 @@ func main() {
 }
 
 func run() error {
-	a, err := newApp()
+	if len(os.Args) <= 1 {
+		return fmt.Errorf("not enough arguments: usage: goblog (serve|static|preview-url <id>)")
+	}
+
+	cfg, err := parseConfig()
 	if err != nil {
-		return fmt.Errorf("new app: %w", err)
+		return err
 	}
 
-	if len(os.Args) <= 1 {
-		return fmt.Errorf("not enough arguments: usage: goblog (serve|static)")
+	// preview urls are signed with the config alone, the store may be locked by a running server
+	if os.Args[1] == "preview-url" {
+		if len(os.Args) <= 2 {
+			return fmt.Errorf("not enough arguments: usage: goblog preview-url <id>")
+		}
+		return printPreviewURL(cfg, os.Args[2])
+	}
+
+	a, err := newApp(cfg)
+	if err != nil {
+		return fmt.Errorf("new app: %w", err)
 	}
+	// closing badger cleanly after every write stopped keeps its value log consistent
+	defer func() {
+		if err := a.close(); err != nil {
+			log.Printf("shutdown: %s", err)
+		}
+	}()
+
+	// ctx is cancelled on SIGINT or SIGTERM to shut down gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	switch os.Args[1] {
 	case "serve":
-		return a.startWebServer()
+		return a.startWebServer(ctx)
 	case "static":
-		return a.startSSG()
+		return a.startSSG(ctx)
 	default:
-		return fmt.Errorf("wrong usage: usage: goblog (serve|static)")
+		return fmt.Errorf("wrong usage: usage: goblog (serve|static|preview-url <id>)")
 	}
 }
 
 type config struct {
+	Provider                string        `env:"PROVIDER" envDefault:"notion"`
+	MarkdownPath            string        `env:"MARKDOWN_PATH" envDefault:"content"`
 	NotionAPIKey            string        `env:"NOTION_API_KEY"`
 	NotionArticleDatabaseID string        `env:"NOTION_ARTICLE_DATABASE_ID"`
+	NotionBaseURL           string        `env:"NOTION_BASE_URL" envDefault:"https://api.notion.com"`
+	NotionMaxRetries        int           `env:"NOTION_MAX_RETRIES" envDefault:"3"`
+	NotionRateLimit         float64       `env:"NOTION_RATE_LIMIT" envDefault:"3"`
+	NotionRequestTimeout    time.Duration `env:"NOTION_REQUEST_TIMEOUT" envDefault:"30s"`
+	SiteURL                 string        `env:"SITE_URL" envDefault:"http://localhost:3000"`
+	SiteTitle               string        `env:"SITE_TITLE" envDefault:"Soheil Ansari"`
+	SiteDescription         string        `env:"SITE_DESCRIPTION"`
+	FeedFullContent         bool          `env:"FEED_FULL_CONTENT" envDefault:"false"`
+	RobotsTxt               string        `env:"ROBOTS_TXT"`
+	IncludeDrafts           bool          `env:"INCLUDE_DRAFTS" envDefault:"false"`
+	PreviewSecret           string        `env:"PREVIEW_SECRET"`
+	RevalidateSecret        string        `env:"REVALIDATE_SECRET"`
 	BadgerDBPath            string        `env:"BADGER_DB_PATH" envDefault:"/tmp/badger"`
 	MaxSeedWorkers          int           `env:"MAX_SEED_WORKERS" envDefault:"10"`
 	SeedInterval            time.Duration `env:"UPDATE_INTERVAL" envDefault:"60s"`
+	UpdateTimeout           time.Duration `env:"UPDATE_TIMEOUT" envDefault:"5m"`
 	ListenAddress           string        `env:"LISTEN_ADDRESS" envDefault:":3000"`
+	ReadTimeout             time.Duration `env:"READ_TIMEOUT" envDefault:"10s"`
+	WriteTimeout            time.Duration `env:"WRITE_TIMEOUT" envDefault:"30s"`
+	IdleTimeout             time.Duration `env:"IDLE_TIMEOUT" envDefault:"120s"`
+	ShutdownTimeout         time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"20s"`
+	CacheControlPages       string        `env:"CACHE_CONTROL_PAGES" envDefault:"public, no-cache"`
+	CacheControlDocuments   string        `env:"CACHE_CONTROL_DOCUMENTS" envDefault:"public, max-age=3600"`
+	CacheControlStatic      string        `env:"CACHE_CONTROL_STATIC" envDefault:"public, max-age=86400"`
+	CacheControlFingerprint string        `env:"CACHE_CONTROL_FINGERPRINTED" envDefault:"public, max-age=31536000, immutable"`
+	CacheControlFiles       string        `env:"CACHE_CONTROL_FILES" envDefault:"public, max-age=31536000, immutable"`
 	DBInMemory              bool          `env:"DB_IN_MEMORY" envDefault:"false"`
 	SSGPath                 string        `env:"SSG_PATH" envDefault:"_site"`
 }
@@ -58,17 +114,45 @@ type app struct {
 	fe       *frontend.Frontend
 	provider pages.Provider
 	store    pages.Store
+	updater  *pages.Updater
+	db       *badger.DB
 	cfg      *config
+	// build identifies the running binary, stored pages rendered by other builds are rebuilt
+	build string
 }
 
-func newApp() (*app, error) {
+// parseConfig parses the config from env
+func parseConfig() (*config, error) {
 	var cfg config
 	if err := env.Parse(&cfg); err != nil {
 		return nil, fmt.Errorf("startup: parse config from env: %w", err)
 	}
+	return &cfg, nil
+}
 
-	notionClient := notion.NewClient(cfg.NotionAPIKey)
-	provider := notionprovider.NewProvider(notionClient, cfg.NotionArticleDatabaseID)
+// newApp creates the app of cfg, opening the badger db and the provider of its pages
+func newApp(cfg *config) (*app, error) {
+	var provider pages.Provider
+	switch cfg.Provider {
+	case "notion":
+		notionClient := notion.NewClient(
+			cfg.NotionAPIKey,
+			notion.WithBaseURL(cfg.NotionBaseURL),
+			notion.WithRetries(cfg.NotionMaxRetries),
+			notion.WithRateLimit(cfg.NotionRateLimit, notion.DefaultRateBurst),
+			notion.WithRequestTimeout(cfg.NotionRequestTimeout),
+		)
+		provider = notionprovider.NewProvider(notionClient, cfg.NotionArticleDatabaseID)
+	case "markdown":
+		provider = markdownprovider.NewProvider(os.DirFS(cfg.MarkdownPath))
+	default:
+		return nil, fmt.Errorf("startup: unknown provider %q: should be notion or markdown", cfg.Provider)
+	}
+
+	build, err := buildVersion()
+	if err != nil {
+		return nil, fmt.Errorf("startup: build version: %w", err)
+	}
 
 	var options badger.Options
 	if cfg.DBInMemory {
@@ -82,27 +166,96 @@ func newApp() (*app, error) {
 	if err != nil {
 		return nil, fmt.Errorf("startup: open badger db: %w", err)
 	}
-	store := repository.New(db)
-
-	assetFiles := assets.New()
-	fe := frontend.New(store, assetFiles)
+	store, err := repository.New(db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("startup: new repository: %w", err)
+	}
 
-	return &app{
-		fe:       fe,
-		cfg:      &cfg,
+	a := &app{
+		cfg:      cfg,
 		provider: provider,
 		store:    store,
-	}, nil
+		db:       db,
+		build:    build,
+	}
+	a.updater = pages.NewUpdater(a.updateStore)
+	a.fe = frontend.New(
+		store,
+		assets.New(),
+		frontend.WithPreview(provider, cfg.PreviewSecret),
+		frontend.WithRevalidation(a.updater.Trigger, cfg.RevalidateSecret),
+		frontend.WithCacheControl(frontend.CacheControl{
+			Pages:         cfg.CacheControlPages,
+			Documents:     cfg.CacheControlDocuments,
+			Static:        cfg.CacheControlStatic,
+			Fingerprinted: cfg.CacheControlFingerprint,
+			Files:         cfg.CacheControlFiles,
+		}),
+	)
+
+	return a, nil
+}
+
+// buildVersion hashes the running executable, the code, templates and embedded assets rendering pages are all compiled
+// into it so any change to them changes the version
+func buildVersion() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("locate executable: %w", err)
+	}
+	f, err := os.Open(exe)
+	if err != nil {
+		return "", fmt.Errorf("open executable: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hash executable: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8]), nil
+}
+
+// close releases the app resources, it should be called once nothing uses the store
+func (a *app) close() error {
+	if err := a.db.Close(); err != nil {
+		return fmt.Errorf("close badger db: %w", err)
+	}
+	return nil
 }
 
-func (a *app) updateStore() error {
-	if err := pages.UpdateStore(a.provider, a.store, a.cfg.MaxSeedWorkers); err != nil {
+// updateStore runs a single store update bounded by the configured update timeout
+func (a *app) updateStore(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, a.cfg.UpdateTimeout)
+	defer cancel()
+
+	site := pages.Site{
+		URL:              a.cfg.SiteURL,
+		Title:            a.cfg.SiteTitle,
+		Description:      a.cfg.SiteDescription,
+		FullContentFeeds: a.cfg.FeedFullContent,
+		Robots:           a.cfg.RobotsTxt,
+		IncludeDrafts:    a.cfg.IncludeDrafts,
+		Build:            a.build,
+	}
+	if err := pages.UpdateStore(ctx, a.provider, a.store, site, a.cfg.MaxSeedWorkers); err != nil {
 		return fmt.Errorf("update store: %w", err)
 	}
 	return nil
 }
 
-func (a *app) startSSG() error {
+// printPreviewURL prints the signed url previewing the article with id
+func printPreviewURL(cfg *config, id string) error {
+	if cfg.PreviewSecret == "" {
+		return fmt.Errorf("PREVIEW_SECRET is not configured")
+	}
+
+	fmt.Printf("%s%s%s?token=%s\n", strings.TrimRight(cfg.SiteURL, "/"), frontend.PreviewPath, id, frontend.PreviewToken(cfg.PreviewSecret, id))
+	return nil
+}
+
+func (a *app) startSSG(ctx context.Context) error {
 	target := a.cfg.SSGPath
 
 	if target == "" {
@@ -120,30 +273,48 @@ func (a *app) startSSG() error {
 	}
 
 	fmt.Println("starting seeding store with provider data")
-	if err := a.updateStore(); err != nil {
+	if err := a.updateStore(ctx); err != nil {
 		return fmt.Errorf("initial store seed: %w", err)
 	}
 	fmt.Println("seed successful")
 
 	fmt.Printf("starting SSG to %s\n", target)
-	if err := a.fe.SSG(target, 0777); err != nil {
+	if err := a.fe.SSG(ctx, target, 0777); err != nil {
 		return fmt.Errorf("frontend SSG: %w", err)
 	}
 	fmt.Printf("successfully generated static site to %s\n", target)
 	return nil
 }
 
-func (a *app) startWebServer() error {
-	if err := a.updateStore(); err != nil {
+func (a *app) startWebServer(ctx context.Context) error {
+	if err := a.updateStore(ctx); err != nil {
 		return fmt.Errorf("initial store seed: %w", err)
 	}
 
-	// start goroutine to keep store updated
+	// updates and the refresher stop with the server, cancelling an in-flight update
+	updateCtx, cancelUpdates := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancelUpdates()
+		wg.Wait()
+	}()
+
+	// run updates triggered by the ticker and revalidation requests one at a time
+	wg.Add(2)
 	go func() {
+		defer wg.Done()
+		a.updater.Run(updateCtx)
+	}()
+	go func() {
+		defer wg.Done()
 		t := time.NewTicker(a.cfg.SeedInterval)
-		for range t.C {
-			if err := a.updateStore(); err != nil {
-				fmt.Printf("update store: %s\n", err)
+		defer t.Stop()
+		for {
+			select {
+			case <-updateCtx.Done():
+				return
+			case <-t.C:
+				a.updater.Trigger()
 			}
 		}
 	}()
@@ -151,9 +322,34 @@ func (a *app) startWebServer() error {
 	mux := http.NewServeMux()
 	a.fe.Routes(mux)
 
-	fmt.Printf("Starting web server on %s\n", a.cfg.ListenAddress)
-	if err := http.ListenAndServe(a.cfg.ListenAddress, mux); err != nil {
+	server := http.Server{
+		Addr:              a.cfg.ListenAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: a.cfg.ReadTimeout,
+		ReadTimeout:       a.cfg.ReadTimeout,
+		WriteTimeout:      a.cfg.WriteTimeout,
+		IdleTimeout:       a.cfg.IdleTimeout,
+	}
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		fmt.Printf("Starting web server on %s\n", a.cfg.ListenAddress)
+		serverErrors <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErrors:
 		return fmt.Errorf("shutdown: website server: %w", err)
+	case <-ctx.Done():
+		fmt.Println("shutdown: draining connections")
+	}
+
+	// drain in-flight requests, the store is closed only after they and the updates are done
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.cfg.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		server.Close()
+		return fmt.Errorf("shutdown: graceful shutdown of website server: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,666 @@
+// Package mathml converts TeX math expressions to MathML so browsers render them without client-side scripts,
+// it supports the commonly used subset of TeX math: scripts, fractions, roots, accents, delimiters, matrices,
+// fonts and the usual symbols
+package mathml
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ErrSyntax is returned for malformed expressions, e.g. unbalanced braces
+	ErrSyntax = errors.New("syntax error")
+	// ErrUnsupported is returned for expressions using commands or environments not supported by the converter
+	ErrUnsupported = errors.New("unsupported")
+)
+
+// Render converts the TeX expression tex to a math element, display renders it as a block like $$ does in TeX,
+// the TeX source is kept in an annotation of the element
+func Render(tex string, display bool) (string, error) {
+	p := &parser{src: []rune(tex)}
+	body, err := p.parseTable("")
+	if err != nil {
+		return "", fmt.Errorf("convert %q: %w", tex, err)
+	}
+
+	mode := "inline"
+	if display {
+		mode = "block"
+	}
+	return fmt.Sprintf(
+		`<math xmlns="http://www.w3.org/1998/Math/MathML" display="%s"><semantics>%s<annotation encoding="application/x-tex">%s</annotation></semantics></math>`,
+		mode, body, html.EscapeString(tex),
+	), nil
+}
+
+// parser converts TeX to MathML while reading it, every parse method returns a single MathML element
+type parser struct {
+	src []rune
+	pos int
+	// font is the font letters and digits are written in, see fonts
+	font string
+}
+
+// atom is a parsed element scripts are attached to
+type atom struct {
+	markup string
+	// limits puts scripts under and over the atom instead of next to it, e.g. \sum
+	limits bool
+	// function is followed by an invisible function application operator, e.g. \sin
+	function bool
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+// skipSpace skips white space and comments, spaces are insignificant in math mode
+func (p *parser) skipSpace() {
+	for !p.eof() {
+		switch r := p.peek(); {
+		case unicode.IsSpace(r):
+			p.pos++
+		case r == '%':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// lookingAt reports whether the source continues with s
+func (p *parser) lookingAt(s string) bool {
+	rs := []rune(s)
+	if p.pos+len(rs) > len(p.src) {
+		return false
+	}
+	return string(p.src[p.pos:p.pos+len(rs)]) == s
+}
+
+// lookingAtCommand reports whether the source continues with the command name
+func (p *parser) lookingAtCommand(name string) bool {
+	if !p.lookingAt(`\` + name) {
+		return false
+	}
+	next := p.pos + 1 + len([]rune(name))
+	return next >= len(p.src) || !unicode.IsLetter(p.src[next])
+}
+
+// atTerminator reports whether the source continues with a token ending a row, the caller decides if it's valid
+func (p *parser) atTerminator() bool {
+	return p.eof() || p.peek() == '}' || p.peek() == '&' || p.lookingAt(`\\`) ||
+		p.lookingAtCommand("end") || p.lookingAtCommand("right")
+}
+
+// parseRow parses atoms until a terminator
+func (p *parser) parseRow() (string, error) {
+	var items []string
+	for {
+		p.skipSpace()
+		if p.atTerminator() {
+			return row(items), nil
+		}
+
+		a, err := p.parseScripted()
+		if err != nil {
+			return "", err
+		}
+		if a.markup != "" {
+			items = append(items, a.markup)
+		}
+		if a.function {
+			items = append(items, "<mo>&#x2061;</mo>")
+		}
+	}
+}
+
+// parseTable parses rows of cells separated by \\ and & up to \end{env}, or the end of the source when env is empty,
+// a single cell is returned as is
+func (p *parser) parseTable(env string) (string, error) {
+	var rows [][]string
+	cells := []string{}
+	for {
+		cell, err := p.parseRow()
+		if err != nil {
+			return "", err
+		}
+		cells = append(cells, cell)
+
+		switch {
+		case p.peek() == '&':
+			p.pos++
+			continue
+		case p.lookingAt(`\\`):
+			p.pos += 2
+			// the optional spacing of \\[2pt] is ignored
+			p.parseOptional()
+			rows = append(rows, cells)
+			cells = []string{}
+			continue
+		case env == "" && p.eof():
+		case env != "" && p.lookingAtCommand("end"):
+			p.pos += len(`\end`)
+			name, err := p.parseRaw()
+			if err != nil {
+				return "", err
+			}
+			if name != env {
+				return "", fmt.Errorf("%w: \\begin{%s} ended by \\end{%s}", ErrSyntax, env, name)
+			}
+		case p.eof():
+			return "", fmt.Errorf("%w: missing \\end{%s}", ErrSyntax, env)
+		default:
+			return "", fmt.Errorf("%w: unexpected %q", ErrSyntax, string(p.src[p.pos:]))
+		}
+		break
+	}
+
+	// a trailing \\ doesn't start a row
+	if len(cells) > 1 || cells[0] != row(nil) || len(rows) == 0 {
+		rows = append(rows, cells)
+	}
+	if env == "" && len(rows) == 1 && len(rows[0]) == 1 {
+		return rows[0][0], nil
+	}
+
+	return table(rows, env)
+}
+
+// table writes rows as an mtable of env with its delimiters around it
+func table(rows [][]string, env string) (string, error) {
+	spec := environments[env]
+	if env == "" {
+		spec.align = "alternate"
+	}
+
+	columns := 0
+	for _, cells := range rows {
+		columns = max(columns, len(cells))
+	}
+	align := make([]string, columns)
+	for i := range align {
+		switch {
+		case spec.align == "alternate" && i%2 == 0:
+			align[i] = "right"
+		case spec.align == "alternate":
+			align[i] = "left"
+		default:
+			align[i] = spec.align
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<mtable columnalign="%s">`, strings.Join(align, " "))
+	for _, cells := range rows {
+		b.WriteString("<mtr>")
+		for _, cell := range cells {
+			fmt.Fprintf(&b, "<mtd>%s</mtd>", cell)
+		}
+		b.WriteString("</mtr>")
+	}
+	b.WriteString("</mtable>")
+
+	if spec.open == "" && spec.close == "" {
+		return b.String(), nil
+	}
+	return fmt.Sprintf("<mrow>%s%s%s</mrow>", fence(spec.open), b.String(), fence(spec.close)), nil
+}
+
+// parseScripted parses an atom with its sub and superscripts
+func (p *parser) parseScripted() (atom, error) {
+	base, err := p.parseAtom(false)
+	if err != nil {
+		return atom{}, err
+	}
+
+	var sub, sup string
+	var primes int
+	for {
+		p.skipSpace()
+		switch {
+		case p.peek() == '^' || p.peek() == '_':
+			script := p.peek()
+			p.pos++
+			arg, err := p.parseArgument()
+			if err != nil {
+				return atom{}, err
+			}
+			if script == '^' {
+				if sup != "" {
+					return atom{}, fmt.Errorf("%w: double superscript", ErrSyntax)
+				}
+				sup = arg
+			} else {
+				if sub != "" {
+					return atom{}, fmt.Errorf("%w: double subscript", ErrSyntax)
+				}
+				sub = arg
+			}
+			continue
+		case p.peek() == '\'':
+			p.pos++
+			primes++
+			continue
+		case p.lookingAtCommand("limits"):
+			p.pos += len(`\limits`)
+			base.limits = true
+			continue
+		case p.lookingAtCommand("nolimits"):
+			p.pos += len(`\nolimits`)
+			base.limits = false
+			continue
+		}
+		break
+	}
+
+	if primes > 0 {
+		prime := fmt.Sprintf("<mo>%s</mo>", strings.Repeat("′", primes))
+		if sup == "" {
+			sup = prime
+		} else {
+			sup = row([]string{prime, sup})
+		}
+	}
+	if sub == "" && sup == "" {
+		return base, nil
+	}
+	if base.markup == "" {
+		base.markup = row(nil)
+	}
+
+	under, over := "msub", "msup"
+	both := "msubsup"
+	if base.limits {
+		under, over, both = "munder", "mover", "munderover"
+	}
+	switch {
+	case sup == "":
+		base.markup = fmt.Sprintf("<%s>%s%s</%s>", under, base.markup, sub, under)
+	case sub == "":
+		base.markup = fmt.Sprintf("<%s>%s%s</%s>", over, base.markup, sup, over)
+	default:
+		base.markup = fmt.Sprintf("<%s>%s%s%s</%s>", both, base.markup, sub, sup, both)
+	}
+	base.limits = false
+	return base, nil
+}
+
+// parseArgument parses the argument of a command or script, a group or a single token
+func (p *parser) parseArgument() (string, error) {
+	p.skipSpace()
+	if p.atTerminator() || p.peek() == '^' || p.peek() == '_' || p.peek() == '\'' {
+		return "", fmt.Errorf("%w: missing argument", ErrSyntax)
+	}
+	a, err := p.parseAtom(true)
+	if err != nil {
+		return "", err
+	}
+	if a.markup == "" {
+		return row(nil), nil
+	}
+	return a.markup, nil
+}
+
+// parseGroup parses a row in braces
+func (p *parser) parseGroup() (string, error) {
+	p.pos++
+	content, err := p.parseRow()
+	if err != nil {
+		return "", err
+	}
+	if p.peek() != '}' {
+		return "", fmt.Errorf("%w: missing }", ErrSyntax)
+	}
+	p.pos++
+	return content, nil
+}
+
+// parseRaw returns the source in braces as is, used for text and environment names
+func (p *parser) parseRaw() (string, error) {
+	p.skipSpace()
+	if p.peek() != '{' {
+		return "", fmt.Errorf("%w: missing {", ErrSyntax)
+	}
+	start := p.pos + 1
+	depth := 0
+	for ; !p.eof(); p.pos++ {
+		switch p.peek() {
+		case '\\':
+			p.pos++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				p.pos++
+				return string(p.src[start : p.pos-1]), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: missing }", ErrSyntax)
+}
+
+// parseOptional returns the source of an optional argument in brackets if there is one
+func (p *parser) parseOptional() (string, bool) {
+	start := p.pos
+	p.skipSpace()
+	if p.peek() != '[' {
+		p.pos = start
+		return "", false
+	}
+	for i := p.pos + 1; i < len(p.src); i++ {
+		if p.src[i] == ']' {
+			arg := string(p.src[p.pos+1 : i])
+			p.pos = i + 1
+			return arg, true
+		}
+	}
+	p.pos = start
+	return "", false
+}
+
+// parseAtom parses a single atom, single reads a single digit instead of a number as TeX does for arguments
+func (p *parser) parseAtom(single bool) (atom, error) {
+	switch r := p.peek(); {
+	case r == '{':
+		content, err := p.parseGroup()
+		return atom{markup: content}, err
+	case r == '\\':
+		return p.parseCommand()
+	case r == '^' || r == '_' || r == '\'':
+		// scripts without a base, e.g. {}^{14}C
+		return atom{}, nil
+	case unicode.IsDigit(r) || r == '.' && p.pos+1 < len(p.src) && unicode.IsDigit(p.src[p.pos+1]):
+		start := p.pos
+		p.pos++
+		for !single && !p.eof() && (unicode.IsDigit(p.peek()) || p.peek() == '.' && p.pos+1 < len(p.src) && unicode.IsDigit(p.src[p.pos+1])) {
+			p.pos++
+		}
+		return atom{markup: fmt.Sprintf("<mn>%s</mn>", p.styled(string(p.src[start:p.pos])))}, nil
+	case unicode.IsLetter(r):
+		p.pos++
+		return atom{markup: p.identifier(string(r))}, nil
+	case r == '~':
+		p.pos++
+		return atom{markup: "<mtext>&#xA0;</mtext>"}, nil
+	default:
+		p.pos++
+		switch r {
+		case '-':
+			r = '−'
+		case '*':
+			r = '∗'
+		}
+		return atom{markup: fmt.Sprintf("<mo>%s</mo>", html.EscapeString(string(r)))}, nil
+	}
+}
+
+// identifier writes letters as an identifier in the current font
+func (p *parser) identifier(letters string) string {
+	if p.font == "normal" {
+		return fmt.Sprintf(`<mi mathvariant="normal">%s</mi>`, html.EscapeString(letters))
+	}
+	return fmt.Sprintf("<mi>%s</mi>", p.styled(letters))
+}
+
+// styled converts s to the alphabet of the current font and escapes it
+func (p *parser) styled(s string) string {
+	if a, ok := alphabets[p.font]; ok {
+		s = strings.Map(a.convert, s)
+	}
+	return html.EscapeString(s)
+}
+
+// parseCommand parses a command and its arguments
+func (p *parser) parseCommand() (atom, error) {
+	p.pos++
+	if p.eof() {
+		return atom{}, fmt.Errorf("%w: trailing \\", ErrSyntax)
+	}
+
+	start := p.pos
+	if unicode.IsLetter(p.peek()) {
+		for !p.eof() && unicode.IsLetter(p.peek()) {
+			p.pos++
+		}
+	} else {
+		p.pos++
+	}
+	name := string(p.src[start:p.pos])
+
+	if s, ok := symbols[name]; ok {
+		return atom{markup: fmt.Sprintf("<%s%s>%s</%s>", s.tag, s.attrs, html.EscapeString(s.char), s.tag)}, nil
+	}
+	if op, ok := largeOperators[name]; ok {
+		return atom{markup: fmt.Sprintf("<mo>%s</mo>", op), limits: true}, nil
+	}
+	if functions[name] {
+		return atom{markup: fmt.Sprintf("<mi>%s</mi>", name), function: true}, nil
+	}
+	if f, ok := limitFunctions[name]; ok {
+		return atom{markup: fmt.Sprintf(`<mo form="prefix" movablelimits="true">%s</mo>`, f), limits: true, function: true}, nil
+	}
+	if width, ok := spaces[name]; ok {
+		return atom{markup: fmt.Sprintf(`<mspace width="%s"/>`, width)}, nil
+	}
+	if accent, ok := accents[name]; ok {
+		arg, err := p.parseArgument()
+		if err != nil {
+			return atom{}, err
+		}
+		mo := fmt.Sprintf(`<mo stretchy="%t">%s</mo>`, accent.stretchy, html.EscapeString(accent.char))
+		// braces take their labels as scripts under and over them
+		if accent.underneath {
+			return atom{markup: fmt.Sprintf(`<munder accentunder="true">%s%s</munder>`, arg, mo), limits: name == "underbrace"}, nil
+		}
+		return atom{markup: fmt.Sprintf(`<mover accent="true">%s%s</mover>`, arg, mo), limits: name == "overbrace"}, nil
+	}
+	if font, ok := fonts[name]; ok {
+		outer := p.font
+		p.font = font
+		arg, err := p.parseArgument()
+		p.font = outer
+		return atom{markup: arg}, err
+	}
+	if size, ok := delimiterSizes[name]; ok {
+		delim, err := p.parseDelimiter()
+		if err != nil {
+			return atom{}, err
+		}
+		return atom{markup: fmt.Sprintf(`<mo stretchy="true" symmetric="true" minsize="%s" maxsize="%s">%s</mo>`, size, size, html.EscapeString(delim))}, nil
+	}
+
+	switch name {
+	case "frac", "dfrac", "tfrac", "cfrac":
+		num, err := p.parseArgument()
+		if err != nil {
+			return atom{}, err
+		}
+		den, err := p.parseArgument()
+		if err != nil {
+			return atom{}, err
+		}
+		return atom{markup: fmt.Sprintf("<mfrac>%s%s</mfrac>", num, den)}, nil
+	case "binom", "dbinom", "tbinom":
+		n, err := p.parseArgument()
+		if err != nil {
+			return atom{}, err
+		}
+		k, err := p.parseArgument()
+		if err != nil {
+			return atom{}, err
+		}
+		return atom{markup: fmt.Sprintf(`<mrow>%s<mfrac linethickness="0">%s%s</mfrac>%s</mrow>`, fence("("), n, k, fence(")"))}, nil
+	case "sqrt":
+		index, hasIndex := p.parseOptional()
+		radicand, err := p.parseArgument()
+		if err != nil {
+			return atom{}, err
+		}
+		if !hasIndex {
+			return atom{markup: fmt.Sprintf("<msqrt>%s</msqrt>", radicand)}, nil
+		}
+		ip := &parser{src: []rune(index), font: p.font}
+		indexMarkup, err := ip.parseRow()
+		if err != nil {
+			return atom{}, err
+		}
+		if !ip.eof() {
+			return atom{}, fmt.Errorf("%w: unexpected %q in root index", ErrSyntax, string(ip.src[ip.pos:]))
+		}
+		return atom{markup: fmt.Sprintf("<mroot>%s%s</mroot>", radicand, indexMarkup)}, nil
+	case "text", "textrm", "textnormal", "textit", "textbf", "textsf", "texttt", "mbox", "hbox":
+		text, err := p.parseRaw()
+		if err != nil {
+			return atom{}, err
+		}
+		return atom{markup: fmt.Sprintf("<mtext>%s</mtext>", html.EscapeString(unescapeText(text)))}, nil
+	case "operatorname":
+		limits := false
+		if p.peek() == '*' {
+			p.pos++
+			limits = true
+		}
+		op, err := p.parseRaw()
+		if err != nil {
+			return atom{}, err
+		}
+		if limits {
+			return atom{markup: fmt.Sprintf(`<mo form="prefix" movablelimits="true">%s</mo>`, html.EscapeString(op)), limits: true, function: true}, nil
+		}
+		return atom{markup: fmt.Sprintf(`<mi mathvariant="normal">%s</mi>`, html.EscapeString(op)), function: true}, nil
+	case "left":
+		return p.parseFenced()
+	case "middle":
+		delim, err := p.parseDelimiter()
+		if err != nil {
+			return atom{}, err
+		}
+		return atom{markup: fmt.Sprintf(`<mo stretchy="true">%s</mo>`, html.EscapeString(delim))}, nil
+	case "not":
+		p.skipSpace()
+		negated, err := p.parseAtom(true)
+		if err != nil {
+			return atom{}, err
+		}
+		op, ok := strings.CutPrefix(negated.markup, "<mo>")
+		if op, ok = strings.CutSuffix(op, "</mo>"); !ok {
+			return atom{}, fmt.Errorf("%w: \\not of a non operator", ErrUnsupported)
+		}
+		op = html.UnescapeString(op)
+		if composed, ok := negations[op]; ok {
+			op = composed
+		} else {
+			op += "\u0338"
+		}
+		negated.markup = fmt.Sprintf("<mo>%s</mo>", html.EscapeString(op))
+		return negated, nil
+	case "begin":
+		env, err := p.parseRaw()
+		if err != nil {
+			return atom{}, err
+		}
+		spec, ok := environments[env]
+		if !ok {
+			return atom{}, fmt.Errorf("%w: environment %s", ErrUnsupported, env)
+		}
+		if spec.align == "spec" {
+			// array column specs are only used for alignment
+			if _, err := p.parseRaw(); err != nil {
+				return atom{}, err
+			}
+		}
+		content, err := p.parseTable(env)
+		return atom{markup: content}, err
+	case "displaystyle", "textstyle", "scriptstyle", "limits", "nolimits", "hline":
+		// styles follow the display mode, limits only matter next to operators and tables have no rules
+		return atom{}, nil
+	}
+
+	return atom{}, fmt.Errorf("%w: command \\%s", ErrUnsupported, name)
+}
+
+// parseFenced parses \left delim ... \right delim after \left
+func (p *parser) parseFenced() (atom, error) {
+	open, err := p.parseDelimiter()
+	if err != nil {
+		return atom{}, err
+	}
+	content, err := p.parseRow()
+	if err != nil {
+		return atom{}, err
+	}
+	if !p.lookingAtCommand("right") {
+		return atom{}, fmt.Errorf("%w: \\left without \\right", ErrSyntax)
+	}
+	p.pos += len(`\right`)
+	closing, err := p.parseDelimiter()
+	if err != nil {
+		return atom{}, err
+	}
+
+	return atom{markup: fmt.Sprintf("<mrow>%s%s%s</mrow>", fence(open), content, fence(closing))}, nil
+}
+
+// parseDelimiter parses the delimiter following \left, \right or a sizing command
+func (p *parser) parseDelimiter() (string, error) {
+	p.skipSpace()
+	if p.eof() {
+		return "", fmt.Errorf("%w: missing delimiter", ErrSyntax)
+	}
+
+	token := string(p.peek())
+	if p.peek() == '\\' {
+		start := p.pos
+		p.pos++
+		for !p.eof() && unicode.IsLetter(p.peek()) {
+			p.pos++
+		}
+		if p.pos == start+1 && !p.eof() {
+			p.pos++
+		}
+		token = string(p.src[start:p.pos])
+	} else {
+		p.pos++
+	}
+
+	delim, ok := delimiters[token]
+	if !ok {
+		return "", fmt.Errorf("%w: delimiter %s", ErrUnsupported, token)
+	}
+	return delim, nil
+}
+
+// fence writes a stretchy delimiter, the null delimiter writes nothing
+func fence(delim string) string {
+	if delim == "" {
+		return ""
+	}
+	return fmt.Sprintf(`<mo fence="true" stretchy="true">%s</mo>`, html.EscapeString(delim))
+}
+
+// row groups items in a single element
+func row(items []string) string {
+	if len(items) == 1 {
+		return items[0]
+	}
+	return "<mrow>" + strings.Join(items, "") + "</mrow>"
+}
+
+// unescapeText replaces the escaped characters of text mode with themselves
+func unescapeText(text string) string {
+	return strings.NewReplacer(`\{`, "{", `\}`, "}", `\%`, "%", `\$`, "$", `\#`, "#", `\&`, "&", `\_`, "_", `\ `, " ").Replace(text)
+}
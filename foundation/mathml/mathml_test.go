@@ -0,0 +1,83 @@
+package mathml
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		tex  string
+		want string
+	}{
+		{"identifiers and numbers", `x + 3.14`, `<mrow><mi>x</mi><mo>+</mo><mn>3.14</mn></mrow>`},
+		{"minus", `a-b`, `<mrow><mi>a</mi><mo>−</mo><mi>b</mi></mrow>`},
+		{"escaping", `a<b`, `<mrow><mi>a</mi><mo>&lt;</mo><mi>b</mi></mrow>`},
+		{"superscript", `e^{i\pi}`, `<msup><mi>e</mi><mrow><mi>i</mi><mi>π</mi></mrow></msup>`},
+		{"single digit argument", `x^23`, `<mrow><msup><mi>x</mi><mn>2</mn></msup><mn>3</mn></mrow>`},
+		{"sub and superscript", `x_i^2`, `<msubsup><mi>x</mi><mi>i</mi><mn>2</mn></msubsup>`},
+		{"primes", `f''`, `<msup><mi>f</mi><mo>′′</mo></msup>`},
+		{"fraction", `\frac{a}{b}`, `<mfrac><mi>a</mi><mi>b</mi></mfrac>`},
+		{"root", `\sqrt[3]{x}`, `<mroot><mi>x</mi><mn>3</mn></mroot>`},
+		{"large operator", `\sum_{i=1}^n i`, `<mrow><munderover><mo>∑</mo><mrow><mi>i</mi><mo>=</mo><mn>1</mn></mrow><mi>n</mi></munderover><mi>i</mi></mrow>`},
+		{"integral", `\int_0^1`, `<msubsup><mo>∫</mo><mn>0</mn><mn>1</mn></msubsup>`},
+		{"function", `\sin x`, `<mrow><mi>sin</mi><mo>&#x2061;</mo><mi>x</mi></mrow>`},
+		{"uppercase greek", `\Omega`, `<mi mathvariant="normal">Ω</mi>`},
+		{"fenced", `\left( x \right.`, `<mrow><mo fence="true" stretchy="true">(</mo><mi>x</mi></mrow>`},
+		{"font", `\mathbb{R}^n`, `<msup><mi>ℝ</mi><mi>n</mi></msup>`},
+		{"text", `\text{if } x`, `<mrow><mtext>if </mtext><mi>x</mi></mrow>`},
+		{"accent", `\hat{x}`, `<mover accent="true"><mi>x</mi><mo stretchy="false">^</mo></mover>`},
+		{"negation", `a \not= b`, `<mrow><mi>a</mi><mo>≠</mo><mi>b</mi></mrow>`},
+		{"matrix", `\begin{pmatrix}1 & 0\\0 & 1\end{pmatrix}`, `<mrow><mo fence="true" stretchy="true">(</mo><mtable columnalign="center center"><mtr><mtd><mn>1</mn></mtd><mtd><mn>0</mn></mtd></mtr><mtr><mtd><mn>0</mn></mtd><mtd><mn>1</mn></mtd></mtr></mtable><mo fence="true" stretchy="true">)</mo></mrow>`},
+		{"lines", `a &= b \\ &= c \\`, `<mtable columnalign="right left"><mtr><mtd><mi>a</mi></mtd><mtd><mrow><mo>=</mo><mi>b</mi></mrow></mtd></mtr><mtr><mtd><mrow></mrow></mtd><mtd><mrow><mo>=</mo><mi>c</mi></mrow></mtd></mtr></mtable>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.tex, false)
+			if err != nil {
+				t.Fatalf("render %q: %s", tt.tex, err)
+			}
+			if !strings.Contains(got, "<semantics>"+tt.want+"<annotation") {
+				t.Errorf("render %q\ngot:  %s\nwant: %s", tt.tex, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderDisplay(t *testing.T) {
+	got, err := Render(`x & y`, true)
+	if err != nil {
+		t.Fatalf("render: %s", err)
+	}
+	if !strings.HasPrefix(got, `<math xmlns="http://www.w3.org/1998/Math/MathML" display="block">`) {
+		t.Errorf("display math should be a block, got: %s", got)
+	}
+	if !strings.Contains(got, `<annotation encoding="application/x-tex">x &amp; y</annotation>`) {
+		t.Errorf("source should be kept as an annotation, got: %s", got)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := []struct {
+		tex  string
+		want error
+	}{
+		{`\frac{a}{b`, ErrSyntax},
+		{`a}`, ErrSyntax},
+		{`x^`, ErrSyntax},
+		{`\left( x`, ErrSyntax},
+		{`\begin{matrix} a`, ErrSyntax},
+		{`\begin{matrix} a \end{pmatrix}`, ErrSyntax},
+		{`\unknowncommand`, ErrUnsupported},
+		{`\begin{tikzpicture}\end{tikzpicture}`, ErrUnsupported},
+	}
+
+	for _, tt := range tests {
+		if _, err := Render(tt.tex, false); !errors.Is(err, tt.want) {
+			t.Errorf("render %q should fail with %v, got: %v", tt.tex, tt.want, err)
+		}
+	}
+}
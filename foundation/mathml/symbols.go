@@ -0,0 +1,228 @@
+package mathml
+
+// symbol is a TeX command rendered as a single MathML token element
+type symbol struct {
+	// tag is the token element, mi for identifiers and mo for operators
+	tag string
+	// char is the character the command stands for
+	char string
+	// attrs are the attributes of the element, e.g. to render upright identifiers
+	attrs string
+}
+
+func identifier(char string) symbol { return symbol{tag: "mi", char: char} }
+func upright(char string) symbol {
+	return symbol{tag: "mi", char: char, attrs: ` mathvariant="normal"`}
+}
+func operator(char string) symbol { return symbol{tag: "mo", char: char} }
+
+// symbols are the commands standing for a single character
+var symbols = map[string]symbol{
+	// lowercase greek letters
+	"alpha": identifier("α"), "beta": identifier("β"), "gamma": identifier("γ"), "delta": identifier("δ"),
+	"epsilon": identifier("ϵ"), "varepsilon": identifier("ε"), "zeta": identifier("ζ"), "eta": identifier("η"),
+	"theta": identifier("θ"), "vartheta": identifier("ϑ"), "iota": identifier("ι"), "kappa": identifier("κ"),
+	"lambda": identifier("λ"), "mu": identifier("μ"), "nu": identifier("ν"), "xi": identifier("ξ"),
+	"omicron": identifier("ο"), "pi": identifier("π"), "varpi": identifier("ϖ"), "rho": identifier("ρ"),
+	"varrho": identifier("ϱ"), "sigma": identifier("σ"), "varsigma": identifier("ς"), "tau": identifier("τ"),
+	"upsilon": identifier("υ"), "phi": identifier("ϕ"), "varphi": identifier("φ"), "chi": identifier("χ"),
+	"psi": identifier("ψ"), "omega": identifier("ω"),
+
+	// uppercase greek letters are upright like in TeX
+	"Gamma": upright("Γ"), "Delta": upright("Δ"), "Theta": upright("Θ"), "Lambda": upright("Λ"),
+	"Xi": upright("Ξ"), "Pi": upright("Π"), "Sigma": upright("Σ"), "Upsilon": upright("Υ"),
+	"Phi": upright("Φ"), "Psi": upright("Ψ"), "Omega": upright("Ω"),
+
+	// identifiers
+	"infty": identifier("∞"), "partial": identifier("∂"), "nabla": upright("∇"), "ell": identifier("ℓ"),
+	"hbar": identifier("ℏ"), "imath": identifier("ı"), "jmath": identifier("ȷ"), "Re": upright("ℜ"),
+	"Im": upright("ℑ"), "aleph": upright("ℵ"), "wp": identifier("℘"), "emptyset": upright("∅"),
+	"varnothing": upright("∅"), "angle": upright("∠"), "prime": identifier("′"), "top": upright("⊤"),
+	"bot": upright("⊥"), "triangle": upright("△"), "Box": upright("□"), "%": upright("%"),
+	"$": upright("$"), "#": upright("#"),
+
+	// binary operators
+	"pm": operator("±"), "mp": operator("∓"), "times": operator("×"), "div": operator("÷"),
+	"cdot": operator("⋅"), "ast": operator("∗"), "star": operator("⋆"), "circ": operator("∘"),
+	"bullet": operator("∙"), "oplus": operator("⊕"), "ominus": operator("⊖"), "otimes": operator("⊗"),
+	"oslash": operator("⊘"), "odot": operator("⊙"), "cap": operator("∩"), "cup": operator("∪"),
+	"sqcap": operator("⊓"), "sqcup": operator("⊔"), "vee": operator("∨"), "lor": operator("∨"),
+	"wedge": operator("∧"), "land": operator("∧"), "setminus": operator("∖"), "wr": operator("≀"),
+	"dagger": operator("†"), "ddagger": operator("‡"), "amalg": operator("⨿"), "backslash": operator("\\"),
+	"forall": operator("∀"), "exists": operator("∃"), "nexists": operator("∄"), "neg": operator("¬"),
+	"lnot": operator("¬"), "&": operator("&"), "_": operator("_"),
+
+	// relations
+	"leq": operator("≤"), "le": operator("≤"), "geq": operator("≥"), "ge": operator("≥"),
+	"neq": operator("≠"), "ne": operator("≠"), "equiv": operator("≡"), "approx": operator("≈"),
+	"sim": operator("∼"), "simeq": operator("≃"), "cong": operator("≅"), "propto": operator("∝"),
+	"ll": operator("≪"), "gg": operator("≫"), "subset": operator("⊂"), "supset": operator("⊃"),
+	"subseteq": operator("⊆"), "supseteq": operator("⊇"), "in": operator("∈"), "notin": operator("∉"),
+	"ni": operator("∋"), "perp": operator("⊥"), "parallel": operator("∥"), "mid": operator("∣"),
+	"models": operator("⊨"), "vdash": operator("⊢"), "dashv": operator("⊣"), "prec": operator("≺"),
+	"succ": operator("≻"), "preceq": operator("⪯"), "succeq": operator("⪰"), "doteq": operator("≐"),
+	"asymp": operator("≍"), "leqslant": operator("⩽"), "geqslant": operator("⩾"), "coloneqq": operator("≔"),
+	"sqsubseteq": operator("⊑"), "sqsupseteq": operator("⊒"),
+
+	// arrows
+	"to": operator("→"), "rightarrow": operator("→"), "leftarrow": operator("←"), "gets": operator("←"),
+	"leftrightarrow": operator("↔"), "Rightarrow": operator("⇒"), "Leftarrow": operator("⇐"),
+	"Leftrightarrow": operator("⇔"), "implies": operator("⟹"), "impliedby": operator("⟸"), "iff": operator("⟺"),
+	"mapsto": operator("↦"), "longrightarrow": operator("⟶"), "longleftarrow": operator("⟵"),
+	"longmapsto": operator("⟼"), "uparrow": operator("↑"), "downarrow": operator("↓"),
+	"hookrightarrow": operator("↪"), "rightharpoonup": operator("⇀"), "nearrow": operator("↗"),
+	"searrow": operator("↘"), "leadsto": operator("⇝"),
+
+	// dots
+	"ldots": operator("…"), "dots": operator("…"), "cdots": operator("⋯"), "vdots": operator("⋮"),
+	"ddots": operator("⋱"),
+
+	// delimiters outside \left and \right
+	"langle": operator("⟨"), "rangle": operator("⟩"), "lfloor": operator("⌊"), "rfloor": operator("⌋"),
+	"lceil": operator("⌈"), "rceil": operator("⌉"), "vert": operator("|"), "Vert": operator("‖"),
+	"lvert": operator("|"), "rvert": operator("|"), "lVert": operator("‖"), "rVert": operator("‖"),
+	"lbrace": operator("{"), "rbrace": operator("}"), "{": operator("{"), "}": operator("}"),
+	"|": operator("‖"),
+
+	// integrals take scripts next to them even in display mode
+	"int": operator("∫"), "iint": operator("∬"), "iiint": operator("∭"), "oint": operator("∮"),
+}
+
+// negations are the precomposed characters of operators negated by \not, others are overlaid by a combining slash
+var negations = map[string]string{
+	"=": "≠", "<": "≮", ">": "≯", "∈": "∉", "≡": "≢", "∼": "≁", "≈": "≉", "≤": "≰", "≥": "≱", "⊂": "⊄",
+	"⊃": "⊅", "⊆": "⊈", "⊇": "⊉", "∃": "∄", "∣": "∤", "∥": "∦",
+}
+
+// largeOperators are the operators taking scripts under and over them in display mode
+var largeOperators = map[string]string{
+	"sum": "∑", "prod": "∏", "coprod": "∐", "bigcup": "⋃", "bigcap": "⋂", "bigvee": "⋁", "bigwedge": "⋀",
+	"bigoplus": "⨁", "bigotimes": "⨂", "bigodot": "⨀", "bigsqcup": "⨆",
+}
+
+// functions are the upright function names, e.g. \sin
+var functions = map[string]bool{
+	"arccos": true, "arcsin": true, "arctan": true, "arg": true, "cos": true, "cosh": true, "cot": true,
+	"coth": true, "csc": true, "deg": true, "dim": true, "exp": true, "hom": true, "ker": true, "lg": true,
+	"ln": true, "log": true, "sec": true, "sin": true, "sinh": true, "tan": true, "tanh": true,
+}
+
+// limitFunctions are the function names taking scripts under them in display mode, e.g. \lim
+var limitFunctions = map[string]string{
+	"lim": "lim", "limsup": "lim sup", "liminf": "lim inf", "max": "max", "min": "min", "sup": "sup",
+	"inf": "inf", "det": "det", "gcd": "gcd", "Pr": "Pr",
+}
+
+// accents are the accent commands and the character put over (or under) their argument
+var accents = map[string]struct {
+	char       string
+	stretchy   bool
+	underneath bool
+}{
+	"hat":            {char: "^"},
+	"widehat":        {char: "^", stretchy: true},
+	"check":          {char: "ˇ"},
+	"tilde":          {char: "~"},
+	"widetilde":      {char: "~", stretchy: true},
+	"acute":          {char: "´"},
+	"grave":          {char: "`"},
+	"dot":            {char: "˙"},
+	"ddot":           {char: "¨"},
+	"breve":          {char: "˘"},
+	"bar":            {char: "¯"},
+	"vec":            {char: "→"},
+	"overline":       {char: "‾", stretchy: true},
+	"overrightarrow": {char: "→", stretchy: true},
+	"overleftarrow":  {char: "←", stretchy: true},
+	"overbrace":      {char: "⏞", stretchy: true},
+	"underline":      {char: "‾", stretchy: true, underneath: true},
+	"underbrace":     {char: "⏟", stretchy: true, underneath: true},
+}
+
+// spaces are the spacing commands and their widths
+var spaces = map[string]string{
+	",": "0.1667em", "thinspace": "0.1667em", ":": "0.2222em", ">": "0.2222em", "medspace": "0.2222em",
+	";": "0.2778em", "thickspace": "0.2778em", " ": "0.3333em", "enspace": "0.5em", "quad": "1em",
+	"qquad": "2em", "!": "-0.1667em", "negthinspace": "-0.1667em",
+}
+
+// delimiters are the delimiters \left, \right and sizing commands accept, "." is the null delimiter
+var delimiters = map[string]string{
+	"(": "(", ")": ")", "[": "[", "]": "]", "|": "|", "/": "/", ".": "", "<": "⟨", ">": "⟩",
+	`\{`: "{", `\}`: "}", `\|`: "‖", `\lbrace`: "{", `\rbrace`: "}", `\langle`: "⟨", `\rangle`: "⟩",
+	`\lfloor`: "⌊", `\rfloor`: "⌋", `\lceil`: "⌈", `\rceil`: "⌉", `\vert`: "|", `\Vert`: "‖",
+	`\lvert`: "|", `\rvert`: "|", `\lVert`: "‖", `\rVert`: "‖", `\backslash`: "\\", `\uparrow`: "↑",
+	`\downarrow`: "↓",
+}
+
+// delimiterSizes are the sizes of the fixed size delimiter commands
+var delimiterSizes = map[string]string{
+	"big": "1.2em", "bigl": "1.2em", "bigr": "1.2em", "bigm": "1.2em",
+	"Big": "1.623em", "Bigl": "1.623em", "Bigr": "1.623em", "Bigm": "1.623em",
+	"bigg": "2.047em", "biggl": "2.047em", "biggr": "2.047em", "biggm": "2.047em",
+	"Bigg": "2.470em", "Biggl": "2.470em", "Biggr": "2.470em", "Biggm": "2.470em",
+}
+
+// environments are the supported matrix like environments and the delimiters around them
+var environments = map[string]struct {
+	open, close string
+	// align is the alignment of columns, alternating right and left alignment for align like environments
+	align string
+}{
+	"matrix":      {align: "center"},
+	"smallmatrix": {align: "center"},
+	"pmatrix":     {open: "(", close: ")", align: "center"},
+	"bmatrix":     {open: "[", close: "]", align: "center"},
+	"Bmatrix":     {open: "{", close: "}", align: "center"},
+	"vmatrix":     {open: "|", close: "|", align: "center"},
+	"Vmatrix":     {open: "‖", close: "‖", align: "center"},
+	"cases":       {open: "{", align: "left"},
+	"aligned":     {align: "alternate"},
+	"align":       {align: "alternate"},
+	"align*":      {align: "alternate"},
+	"split":       {align: "alternate"},
+	"gathered":    {align: "center"},
+	"gather":      {align: "center"},
+	"gather*":     {align: "center"},
+	"array":       {align: "spec"},
+}
+
+// alphabet is a unicode mathematical alphabet letters and digits are mapped to by font commands
+type alphabet struct {
+	upper, lower, digits rune
+	// exceptions are the letters encoded outside the alphabet block
+	exceptions map[rune]rune
+}
+
+// alphabets are the alphabets of font commands
+var alphabets = map[string]alphabet{
+	"bold":          {upper: 0x1D400, lower: 0x1D41A, digits: 0x1D7CE},
+	"double-struck": {upper: 0x1D538, lower: 0x1D552, digits: 0x1D7D8, exceptions: map[rune]rune{'C': 'ℂ', 'H': 'ℍ', 'N': 'ℕ', 'P': 'ℙ', 'Q': 'ℚ', 'R': 'ℝ', 'Z': 'ℤ'}},
+	"script":        {upper: 0x1D49C, lower: 0x1D4B6, exceptions: map[rune]rune{'B': 'ℬ', 'E': 'ℰ', 'F': 'ℱ', 'H': 'ℋ', 'I': 'ℐ', 'L': 'ℒ', 'M': 'ℳ', 'R': 'ℛ', 'e': 'ℯ', 'g': 'ℊ', 'o': 'ℴ'}},
+	"fraktur":       {upper: 0x1D504, lower: 0x1D51E, exceptions: map[rune]rune{'C': 'ℭ', 'H': 'ℌ', 'I': 'ℑ', 'R': 'ℜ', 'Z': 'ℨ'}},
+	"sans-serif":    {upper: 0x1D5A0, lower: 0x1D5BA, digits: 0x1D7E2},
+	"monospace":     {upper: 0x1D670, lower: 0x1D68A, digits: 0x1D7F6},
+}
+
+// fonts are the font commands and the font of their argument, normal is upright and italic is the default
+var fonts = map[string]string{
+	"mathbf": "bold", "boldsymbol": "bold", "bm": "bold", "mathbb": "double-struck", "mathcal": "script",
+	"mathscr": "script", "mathfrak": "fraktur", "mathsf": "sans-serif", "mathtt": "monospace",
+	"mathrm": "normal", "mathup": "normal", "mathit": "italic",
+}
+
+// convert maps r to the alphabet, characters the alphabet doesn't have are returned as is
+func (a alphabet) convert(r rune) rune {
+	if e, ok := a.exceptions[r]; ok {
+		return e
+	}
+	switch {
+	case r >= 'A' && r <= 'Z':
+		return a.upper + r - 'A'
+	case r >= 'a' && r <= 'z':
+		return a.lower + r - 'a'
+	case r >= '0' && r <= '9' && a.digits != 0:
+		return a.digits + r - '0'
+	}
+	return r
+}
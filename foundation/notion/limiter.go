@@ -0,0 +1,51 @@
+package notion
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// limiter is a token bucket rate limiter, a token is added every interval up to burst tokens
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	burst    float64
+	tokens   float64
+	last     time.Time
+}
+
+// newLimiter creates a limiter allowing rps requests per second on average with bursts of burst requests
+func newLimiter(rps float64, burst int) *limiter {
+	return &limiter{
+		interval: time.Duration(float64(time.Second) / rps),
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+// reserve takes a token from the bucket and reports how long the caller should wait before using it
+func (l *limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+float64(now.Sub(l.last))/float64(l.interval))
+	l.last = now
+
+	// tokens go negative when the bucket is empty, so concurrent callers queue up one interval apart
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens * float64(l.interval))
+}
+
+// wait blocks until the caller is allowed to send a request or ctx is done
+func (l *limiter) wait(ctx context.Context) error {
+	if d := l.reserve(); d > 0 {
+		return sleep(ctx, d)
+	}
+	return ctx.Err()
+}
@@ -1,31 +1,61 @@
-// Package notionprovider provides a client to call notionprovider API
+// Package notion provides a client to call notion API
 package notion
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
-// Client is a notionprovider client that can send requests to notionprovider API
+// Client is a notion client that can send requests to notion API
 type Client struct {
+	base          string
 	apiKey        string
 	version       string
 	notionVersion string
+	maxRetries    int
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+	timeout       time.Duration
+	limiter       *limiter
+	httpClient    *http.Client
+	userAgent     string
+	hooks         []RequestHook
 }
 
 const APIVersion = "v1"
 const Version = "2022-06-28"
 const Base = "https://api.notion.com"
 
-// NewClient creates a new notionprovider client with default versions set
-func NewClient(apiKey string) *Client {
-	return &Client{
+// MaxPageSize is the largest page size notion API accepts on paginated endpoints
+const MaxPageSize = 100
+
+// NewClient creates a new notionprovider client with default versions, retries and rate limit set, opts are applied in order
+func NewClient(apiKey string, opts ...Option) *Client {
+	c := &Client{
+		base:          Base,
 		apiKey:        apiKey,
 		version:       APIVersion,
 		notionVersion: Version,
+		maxRetries:    DefaultMaxRetries,
+		minBackoff:    DefaultMinBackoff,
+		maxBackoff:    DefaultMaxBackoff,
+		limiter:       newLimiter(DefaultRateLimit, DefaultRateBurst),
+		httpClient:    http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
 // NotionError represents an error that notionprovider API responded with when called
@@ -39,19 +69,105 @@ func (ne NotionError) Error() string {
 	return ne.Message
 }
 
-func (c *Client) Request(method string, path string, body io.Reader, value any) error {
-	url := fmt.Sprintf("%s/%s%s", Base, c.version, path)
-	req, err := http.NewRequest(method, url, body)
+// retryableError is a failed request attempt that can be retried, after is the wait the server asked for if any
+type retryableError struct {
+	err   error
+	after time.Duration
+}
+
+func (re *retryableError) Error() string {
+	return re.err.Error()
+}
+
+func (re *retryableError) Unwrap() error {
+	return re.err
+}
+
+// Request sends a request to notion API and decodes the response body into value,
+// rate limited and transient failures are retried with exponential backoff until ctx is done
+func (c *Client) Request(ctx context.Context, method string, path string, body io.Reader, value any) error {
+	url := fmt.Sprintf("%s/%s%s", c.base, c.version, path)
+
+	// body is read once so it can be sent again on retries
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = io.ReadAll(body); err != nil {
+			return fmt.Errorf("read request body: url: %s: %w", url, err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := c.do(ctx, method, url, payload, value)
+
+		var re *retryableError
+		if err == nil || !errors.As(err, &re) || attempt >= c.maxRetries {
+			return err
+		}
+
+		wait := re.after
+		if wait <= 0 {
+			wait = c.backoff(attempt)
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return fmt.Errorf("wait for retry: %w: %w", err, re.err)
+		}
+	}
+}
+
+// sleep pauses for d or until ctx is done, whichever happens first
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// do sends a single attempt of a request, bounded by the client request timeout if set
+func (c *Client) do(ctx context.Context, method string, url string, payload []byte, value any) error {
+	if c.limiter != nil {
+		if err := c.limiter.wait(ctx); err != nil {
+			return fmt.Errorf("wait for rate limiter: %w", err)
+		}
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return fmt.Errorf("construct construct notionprovider request: url: %s: %w", url, err)
 	}
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
 	req.Header.Add("Notion-Version", c.notionVersion)
 	req.Header.Add("Content-Type", "application/json")
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	for _, hook := range c.hooks {
+		hook(req)
+	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("notionprovider request: url: %s: %w", url, err)
+		// the caller giving up is final, a timed out attempt is not
+		if ctx.Err() != nil && !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("notionprovider request: url: %s: %w", url, err)
+		}
+		return &retryableError{err: fmt.Errorf("notionprovider request: url: %s: %w", url, err)}
 	}
 
 	defer resp.Body.Close()
@@ -59,9 +175,18 @@ func (c *Client) Request(method string, path string, body io.Reader, value any)
 	if resp.StatusCode >= http.StatusBadRequest {
 		var ne NotionError
 		if err := dec.Decode(&ne); err != nil {
-			return fmt.Errorf("decode error body response: %w", err)
+			err = fmt.Errorf("decode error body response: status code %d: %w", resp.StatusCode, err)
+			if retryable(resp.StatusCode) {
+				return &retryableError{err: err, after: retryAfter(resp.Header)}
+			}
+			return err
+		}
+
+		err := fmt.Errorf("notionprovider request: status code %d: %w", resp.StatusCode, ne)
+		if retryable(resp.StatusCode) {
+			return &retryableError{err: err, after: retryAfter(resp.Header)}
 		}
-		return fmt.Errorf("notionprovider request: status code %d: %w", resp.StatusCode, ne)
+		return err
 	}
 
 	if err := dec.Decode(value); err != nil {
@@ -70,3 +195,106 @@ func (c *Client) Request(method string, path string, body io.Reader, value any)
 
 	return nil
 }
+
+// retryable reports whether a response with status code is worth retrying
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses the Retry-After header in either seconds or HTTP date form, zero means no wait was requested
+func retryAfter(h http.Header) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// backoff returns the wait before retrying attempt, exponentially growing from minBackoff up to maxBackoff with full jitter
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.maxBackoff
+	if attempt < 32 {
+		d = min(c.maxBackoff, c.minBackoff<<attempt)
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// List is a single page of a paginated notion API response
+type List[T any] struct {
+	Results    []T     `json:"results"`
+	HasMore    bool    `json:"has_more"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// RequestAll requests every page of a paginated endpoint and returns all the results.
+// GET requests carry the cursor as query parameters and any other method carries it in body,
+// which may be nil when the endpoint needs no other parameters.
+func RequestAll[T any](ctx context.Context, c *Client, method string, path string, body map[string]any) ([]T, error) {
+	var results []T
+	var cursor string
+	for {
+		page, err := requestPage[T](ctx, c, method, path, body, cursor)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, page.Results...)
+
+		if !page.HasMore || page.NextCursor == nil || *page.NextCursor == "" {
+			return results, nil
+		}
+		cursor = *page.NextCursor
+	}
+}
+
+// requestPage requests a single page of a paginated endpoint starting at cursor
+func requestPage[T any](ctx context.Context, c *Client, method string, path string, body map[string]any, cursor string) (*List[T], error) {
+	var page List[T]
+	if method == http.MethodGet {
+		query := url.Values{}
+		query.Set("page_size", fmt.Sprint(MaxPageSize))
+		if cursor != "" {
+			query.Set("start_cursor", cursor)
+		}
+
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		if err := c.Request(ctx, method, path+sep+query.Encode(), nil, &page); err != nil {
+			return nil, fmt.Errorf("request page at cursor %q: %w", cursor, err)
+		}
+		return &page, nil
+	}
+
+	params := make(map[string]any, len(body)+2)
+	for k, v := range body {
+		params[k] = v
+	}
+	params["page_size"] = MaxPageSize
+	if cursor != "" {
+		params["start_cursor"] = cursor
+	}
+
+	b, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("encode request body: %w", err)
+	}
+	if err := c.Request(ctx, method, path, bytes.NewReader(b), &page); err != nil {
+		return nil, fmt.Errorf("request page at cursor %q: %w", cursor, err)
+	}
+	return &page, nil
+}
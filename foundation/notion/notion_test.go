@@ -0,0 +1,235 @@
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// pagedServer serves total numbered results split in pages of pageSize, cursors are the index of the next result
+func pagedServer(t *testing.T, total int, pageSize int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var cursor string
+		switch r.Method {
+		case http.MethodGet:
+			cursor = r.URL.Query().Get("start_cursor")
+		case http.MethodPost:
+			var body struct {
+				StartCursor string `json:"start_cursor"`
+				Filter      any    `json:"filter"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("decode request body: %s", err)
+			}
+			if body.Filter == nil {
+				t.Error("request body parameters should be kept on every page")
+			}
+			cursor = body.StartCursor
+		}
+
+		start := 0
+		if cursor != "" {
+			var err error
+			if start, err = strconv.Atoi(cursor); err != nil {
+				t.Errorf("invalid cursor: %s", cursor)
+			}
+		}
+		end := min(start+pageSize, total)
+
+		page := List[int]{HasMore: end < total}
+		for i := start; i < end; i++ {
+			page.Results = append(page.Results, i)
+		}
+		if page.HasMore {
+			next := fmt.Sprint(end)
+			page.NextCursor = &next
+		}
+
+		if err := json.NewEncoder(w).Encode(page); err != nil {
+			t.Errorf("encode page: %s", err)
+		}
+	}))
+}
+
+func TestRequestAll(t *testing.T) {
+	const total = 250
+	srv := pagedServer(t, total, MaxPageSize)
+	defer srv.Close()
+
+	c := NewClient("test", WithBaseURL(srv.URL), WithRateLimit(0, 0))
+
+	tests := []struct {
+		name   string
+		method string
+		body   map[string]any
+	}{
+		{"get", http.MethodGet, nil},
+		{"post", http.MethodPost, map[string]any{"filter": map[string]any{"property": "Type"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := RequestAll[int](context.Background(), c, tt.method, "/blocks/id/children", tt.body)
+			if err != nil {
+				t.Fatalf("request all: %s", err)
+			}
+
+			if len(results) != total {
+				t.Fatalf("should return all %d results, got: %d", total, len(results))
+			}
+			for i, r := range results {
+				if r != i {
+					t.Fatalf("results should be in order, result %d is %d", i, r)
+				}
+			}
+		})
+	}
+}
+
+func TestRequestRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int
+		status     int
+		retryAfter string
+		wantErr    bool
+	}{
+		{"rate limited", 2, http.StatusTooManyRequests, "0", false},
+		{"unavailable", 2, http.StatusServiceUnavailable, "", false},
+		{"exhausted", 4, http.StatusBadGateway, "", true},
+		{"not retryable", 1, http.StatusBadRequest, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if int(calls.Add(1)) <= tt.failures {
+					if tt.retryAfter != "" {
+						w.Header().Set("Retry-After", tt.retryAfter)
+					}
+					w.WriteHeader(tt.status)
+					fmt.Fprintf(w, `{"status": %d, "code": "test_error", "message": "test error"}`, tt.status)
+					return
+				}
+				fmt.Fprint(w, `{"ok": true}`)
+			}))
+			defer srv.Close()
+
+			c := NewClient("test", WithBaseURL(srv.URL), WithRetries(3), WithBackoff(time.Millisecond, 5*time.Millisecond), WithRateLimit(0, 0))
+
+			var resp struct {
+				OK bool `json:"ok"`
+			}
+			err := c.Request(context.Background(), http.MethodGet, "/users/me", nil, &resp)
+			if tt.wantErr {
+				var ne NotionError
+				if !errors.As(err, &ne) || ne.Status != tt.status {
+					t.Fatalf("should fail with notion error of status %d, got: %v", tt.status, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("request should succeed after retries: %s", err)
+			}
+			if !resp.OK {
+				t.Error("response should be decoded after retries")
+			}
+			if int(calls.Load()) != tt.failures+1 {
+				t.Errorf("should be called %d times, called %d", tt.failures+1, calls.Load())
+			}
+		})
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(100, 2)
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := l.wait(context.Background()); err != nil {
+			t.Fatalf("wait: %s", err)
+		}
+	}
+
+	// 2 requests are sent right away as a burst, the rest are spaced 10ms apart
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("limiter should space requests after the burst, 6 requests took %s", elapsed)
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"status": 429, "code": "rate_limited", "message": "rate limited"}`)
+	}))
+	defer srv.Close()
+
+	c := NewClient("test", WithBaseURL(srv.URL), WithRateLimit(0, 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := c.Request(ctx, http.MethodGet, "/users/me", nil, &struct{}{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("should fail with context deadline, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("should stop waiting for retry when context is done, took %s", elapsed)
+	}
+}
+
+func TestClientOptions(t *testing.T) {
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		if r.URL.Path != "/v1/users/me" {
+			t.Errorf("request path should be relative to base url, got: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer srv.Close()
+
+	var hooked int
+	c := NewClient(
+		"secret",
+		WithBaseURL(srv.URL+"/"),
+		WithHTTPClient(&http.Client{Timeout: time.Second}),
+		WithUserAgent("goblog-test"),
+		WithNotionVersion("2099-01-01"),
+		WithRequestHook(func(req *http.Request) {
+			hooked++
+			req.Header.Set("X-Test", "hooked")
+		}),
+		WithRateLimit(0, 0),
+	)
+
+	if err := c.Request(context.Background(), http.MethodGet, "/users/me", nil, &struct{}{}); err != nil {
+		t.Fatalf("request: %s", err)
+	}
+
+	want := map[string]string{
+		"Authorization":  "Bearer secret",
+		"User-Agent":     "goblog-test",
+		"Notion-Version": "2099-01-01",
+		"X-Test":         "hooked",
+	}
+	for k, v := range want {
+		if got := header.Get(k); got != v {
+			t.Errorf("header %s should be %q, got: %q", k, v, got)
+		}
+	}
+	if hooked != 1 {
+		t.Errorf("request hook should be called once, called %d times", hooked)
+	}
+}
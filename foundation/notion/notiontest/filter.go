@@ -0,0 +1,172 @@
+package notiontest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// matches reports whether page satisfies a database query filter, a nil filter matches every page.
+// Compound and/or filters and the select, status, multi_select, rich_text, title, checkbox and date
+// property filters are supported, anything else is reported as an error like notion API does
+func matches(page Object, filter map[string]any) (bool, error) {
+	if filter == nil {
+		return true, nil
+	}
+
+	if and, ok := filter["and"].([]any); ok {
+		for _, f := range and {
+			m, err := matches(page, asMap(f))
+			if err != nil || !m {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	if or, ok := filter["or"].([]any); ok {
+		for _, f := range or {
+			m, err := matches(page, asMap(f))
+			if err != nil || m {
+				return m, err
+			}
+		}
+		return false, nil
+	}
+
+	name, ok := filter["property"].(string)
+	if !ok {
+		return false, fmt.Errorf("body.filter should define a property or a compound filter")
+	}
+	property := asMap(asMap(page["properties"])[name])
+
+	for kind, condition := range filter {
+		if kind == "property" {
+			continue
+		}
+
+		var value any
+		switch kind {
+		case "select", "status":
+			if v := asMap(property[kind]); v != nil {
+				value = v["name"]
+			}
+		case "multi_select":
+			var names []string
+			for _, v := range asSlice(property[kind]) {
+				names = append(names, fmt.Sprint(asMap(v)["name"]))
+			}
+			value = names
+		case "rich_text", "title":
+			var s strings.Builder
+			for _, t := range asSlice(property[kind]) {
+				s.WriteString(fmt.Sprint(asMap(t)["plain_text"]))
+			}
+			value = s.String()
+		case "checkbox":
+			value = property[kind]
+		case "date":
+			if v := asMap(property[kind]); v != nil {
+				value = v["start"]
+			}
+		default:
+			return false, fmt.Errorf("body.filter.%s is not supported by notiontest", kind)
+		}
+
+		return compare(kind, value, asMap(condition))
+	}
+
+	return false, fmt.Errorf("body.filter.%s should define a property filter", name)
+}
+
+// compare checks a single property value against a filter condition e.g. {"equals": "Article"}
+func compare(kind string, value any, condition map[string]any) (bool, error) {
+	for op, operand := range condition {
+		switch op {
+		case "is_empty":
+			return isEmpty(value) == (operand == true), nil
+		case "is_not_empty":
+			return !isEmpty(value) == (operand == true), nil
+		}
+
+		switch kind {
+		case "multi_select":
+			names, _ := value.([]string)
+			var found bool
+			for _, name := range names {
+				found = found || name == operand
+			}
+			switch op {
+			case "contains":
+				return found, nil
+			case "does_not_contain":
+				return !found, nil
+			}
+		case "date":
+			if value == nil {
+				return false, nil
+			}
+			v, err := parseDate(fmt.Sprint(value))
+			if err != nil {
+				return false, err
+			}
+			o, err := parseDate(fmt.Sprint(operand))
+			if err != nil {
+				return false, err
+			}
+			switch op {
+			case "equals":
+				return v.Equal(o), nil
+			case "before":
+				return v.Before(o), nil
+			case "after":
+				return v.After(o), nil
+			case "on_or_before":
+				return !v.After(o), nil
+			case "on_or_after":
+				return !v.Before(o), nil
+			}
+		default:
+			switch op {
+			case "equals":
+				return value == operand, nil
+			case "does_not_equal":
+				return value != operand, nil
+			case "contains":
+				return strings.Contains(fmt.Sprint(value), fmt.Sprint(operand)), nil
+			}
+		}
+
+		return false, fmt.Errorf("%s filter condition %s is not supported by notiontest", kind, op)
+	}
+
+	return false, fmt.Errorf("%s filter should define a condition", kind)
+}
+
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+func isEmpty(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []string:
+		return len(v) == 0
+	}
+	return false
+}
+
+func asMap(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+func asSlice(v any) []any {
+	s, _ := v.([]any)
+	return s
+}
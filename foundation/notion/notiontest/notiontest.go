@@ -0,0 +1,326 @@
+// Package notiontest provides an in-process fake of notion API serving pages and blocks from fixtures for tests
+package notiontest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/so-heil/goblog/foundation/notion"
+)
+
+// Object is a notion object (page or block) as it is sent over the wire
+type Object = map[string]any
+
+// Fixture is the content a Server serves, it can be loaded from JSON files
+type Fixture struct {
+	// Databases maps a database id to the pages it contains
+	Databases map[string][]Object `json:"databases"`
+	// Children maps a page or block id to its child blocks
+	Children map[string][]Object `json:"children"`
+}
+
+type failure struct {
+	status int
+	code   string
+}
+
+// Server is a fake notion API, it serves database queries, block children, page retrieval and updates
+// with cursor based pagination, every method is safe for concurrent use
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	pageSize  int
+	databases map[string][]string
+	pages     map[string]Object
+	children  map[string][]Object
+	failures  []failure
+	requests  int
+}
+
+// NewServer starts a fake notion API server with no content, it should be closed when the test is done
+func NewServer() *Server {
+	s := &Server{
+		pageSize:  notion.MaxPageSize,
+		databases: make(map[string][]string),
+		pages:     make(map[string]Object),
+		children:  make(map[string][]Object),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Client returns a notion client sending requests to the server, client side rate limiting is disabled unless set by opts
+func (s *Server) Client(opts ...notion.Option) *notion.Client {
+	opts = append([]notion.Option{
+		notion.WithBaseURL(s.URL),
+		notion.WithRateLimit(0, 0),
+		notion.WithBackoff(time.Millisecond, 10*time.Millisecond),
+	}, opts...)
+	return notion.NewClient("notiontest", opts...)
+}
+
+// SetPageSize caps the number of results in a page of paginated responses, useful to exercise pagination with small fixtures
+func (s *Server) SetPageSize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pageSize = n
+}
+
+// AddPage adds page to the database with databaseID, page should have an "id"
+func (s *Server) AddPage(databaseID string, page Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	page = copyObject(page)
+	id := fmt.Sprint(page["id"])
+	s.databases[databaseID] = append(s.databases[databaseID], id)
+	s.pages[id] = page
+}
+
+// SetChildren replaces the child blocks of the page or block with parentID
+func (s *Server) SetChildren(parentID string, blocks ...Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	children := make([]Object, len(blocks))
+	for i, block := range blocks {
+		children[i] = copyObject(block)
+		if _, ok := children[i]["has_children"]; !ok {
+			children[i]["has_children"] = false
+		}
+	}
+	s.children[parentID] = children
+}
+
+// Load adds the content of fixture to the server
+func (s *Server) Load(fixture Fixture) {
+	for databaseID, pages := range fixture.Databases {
+		for _, page := range pages {
+			s.AddPage(databaseID, page)
+		}
+	}
+	for parentID, blocks := range fixture.Children {
+		s.SetChildren(parentID, blocks...)
+	}
+}
+
+// LoadFile adds the content of the JSON encoded Fixture in file to the server
+func (s *Server) LoadFile(file string) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("read fixture: %w", err)
+	}
+
+	var fixture Fixture
+	if err := json.Unmarshal(b, &fixture); err != nil {
+		return fmt.Errorf("decode fixture %s: %w", file, err)
+	}
+	s.Load(fixture)
+
+	return nil
+}
+
+// Fail makes the next times requests fail with status and notion error code, a 429 is sent with a zero Retry-After
+func (s *Server) Fail(times int, status int, code string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i < times; i++ {
+		s.failures = append(s.failures, failure{status: status, code: code})
+	}
+}
+
+// Requests returns the number of requests the server has received
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// Page returns a copy of the page with id as it is currently stored
+func (s *Server) Page(id string) (Object, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	page, ok := s.pages[id]
+	if !ok {
+		return nil, false
+	}
+	return copyObject(page), true
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "API token is invalid.")
+		return
+	}
+
+	if len(s.failures) > 0 {
+		f := s.failures[0]
+		s.failures = s.failures[1:]
+		if f.status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "0")
+		}
+		writeError(w, f.status, f.code, "notiontest: injected failure")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/"+notion.APIVersion), "/"), "/")
+	switch {
+	case len(parts) == 3 && parts[0] == "databases" && parts[2] == "query" && r.Method == http.MethodPost:
+		s.queryDatabase(w, r, parts[1])
+	case len(parts) == 3 && parts[0] == "blocks" && parts[2] == "children" && r.Method == http.MethodGet:
+		s.blockChildren(w, r, parts[1])
+	case len(parts) == 2 && parts[0] == "pages" && r.Method == http.MethodGet:
+		s.retrievePage(w, parts[1])
+	case len(parts) == 2 && parts[0] == "pages" && r.Method == http.MethodPatch:
+		s.updatePage(w, r, parts[1])
+	default:
+		writeError(w, http.StatusBadRequest, "invalid_request_url", "Invalid request URL.")
+	}
+}
+
+func (s *Server) queryDatabase(w http.ResponseWriter, r *http.Request, databaseID string) {
+	ids, ok := s.databases[databaseID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "object_not_found", fmt.Sprintf("Could not find database with ID: %s.", databaseID))
+		return
+	}
+
+	var body struct {
+		Filter      map[string]any `json:"filter"`
+		StartCursor string         `json:"start_cursor"`
+		PageSize    int            `json:"page_size"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_json", err.Error())
+			return
+		}
+	}
+
+	var results []Object
+	for _, id := range ids {
+		page := s.pages[id]
+		match, err := matches(page, body.Filter)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+			return
+		}
+		if match {
+			results = append(results, page)
+		}
+	}
+
+	s.writePage(w, results, body.StartCursor, body.PageSize)
+}
+
+func (s *Server) blockChildren(w http.ResponseWriter, r *http.Request, parentID string) {
+	blocks, ok := s.children[parentID]
+	if !ok {
+		if _, isPage := s.pages[parentID]; !isPage {
+			writeError(w, http.StatusNotFound, "object_not_found", fmt.Sprintf("Could not find block with ID: %s.", parentID))
+			return
+		}
+	}
+
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	s.writePage(w, blocks, r.URL.Query().Get("start_cursor"), pageSize)
+}
+
+func (s *Server) retrievePage(w http.ResponseWriter, id string) {
+	page, ok := s.pages[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "object_not_found", fmt.Sprintf("Could not find page with ID: %s.", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// updatePage merges the properties in the request body into the page properties and bumps its last edited time
+func (s *Server) updatePage(w http.ResponseWriter, r *http.Request, id string) {
+	page, ok := s.pages[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "object_not_found", fmt.Sprintf("Could not find page with ID: %s.", id))
+		return
+	}
+
+	var body struct {
+		Properties map[string]any `json:"properties"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+
+	properties, _ := page["properties"].(map[string]any)
+	if properties == nil {
+		properties = make(map[string]any)
+		page["properties"] = properties
+	}
+	for name, value := range body.Properties {
+		properties[name] = value
+	}
+	page["last_edited_time"] = time.Now().UTC().Format(time.RFC3339Nano)
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// writePage writes the page of results starting at cursor, cursors are the index of the first result of a page
+func (s *Server) writePage(w http.ResponseWriter, results []Object, cursor string, pageSize int) {
+	if pageSize <= 0 || pageSize > s.pageSize {
+		pageSize = s.pageSize
+	}
+
+	start := 0
+	if cursor != "" {
+		var err error
+		if start, err = strconv.Atoi(cursor); err != nil || start < 0 || start > len(results) {
+			writeError(w, http.StatusBadRequest, "validation_error", fmt.Sprintf("start_cursor %q is invalid.", cursor))
+			return
+		}
+	}
+	end := min(start+pageSize, len(results))
+
+	resp := map[string]any{
+		"object":      "list",
+		"results":     append([]Object{}, results[start:end]...),
+		"has_more":    end < len(results),
+		"next_cursor": nil,
+	}
+	if end < len(results) {
+		resp["next_cursor"] = strconv.Itoa(end)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	writeJSON(w, status, notion.NotionError{Status: status, Code: code, Message: message})
+}
+
+// copyObject deep copies obj through a JSON round trip, which also normalizes nested values to their decoded JSON types
+func copyObject(obj Object) Object {
+	b, _ := json.Marshal(obj)
+	var c Object
+	json.Unmarshal(b, &c)
+	return c
+}
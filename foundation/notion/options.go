@@ -0,0 +1,96 @@
+package notion
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxRetries is the number of times a failed request is retried by default
+	DefaultMaxRetries = 3
+	// DefaultMinBackoff is the wait before the first retry, doubled on every following retry
+	DefaultMinBackoff = 500 * time.Millisecond
+	// DefaultMaxBackoff caps the wait between retries
+	DefaultMaxBackoff = 30 * time.Second
+	// DefaultRateLimit is the average number of requests per second notion API allows for an integration
+	DefaultRateLimit = 3
+	// DefaultRateBurst is the number of requests that can be sent at once before the rate limit applies
+	DefaultRateBurst = 3
+)
+
+// Option configures a Client
+type Option func(c *Client)
+
+// WithRetries sets the number of times a rate limited or transiently failed request is retried, 0 disables retries
+func WithRetries(maxRetries int) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+	}
+}
+
+// WithBackoff sets the bounds of the exponential backoff between retries,
+// a Retry-After header sent by notion takes precedence over it
+func WithBackoff(minWait time.Duration, maxWait time.Duration) Option {
+	return func(c *Client) {
+		c.minBackoff = minWait
+		c.maxBackoff = maxWait
+	}
+}
+
+// WithRequestTimeout bounds every request attempt to d, zero leaves attempts bounded only by the caller's context
+func WithRequestTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithRateLimit limits the client to rps requests per second on average with bursts of up to burst requests,
+// a non-positive rps disables client side rate limiting
+func WithRateLimit(rps float64, burst int) Option {
+	return func(c *Client) {
+		if rps <= 0 {
+			c.limiter = nil
+			return
+		}
+		c.limiter = newLimiter(rps, max(burst, 1))
+	}
+}
+
+// WithBaseURL points the client at base instead of notion API, e.g. a local fake or a recording proxy
+func WithBaseURL(base string) Option {
+	return func(c *Client) {
+		c.base = strings.TrimRight(base, "/")
+	}
+}
+
+// WithHTTPClient sends requests through hc instead of http.DefaultClient, used to set transports and timeouts
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithNotionVersion overrides the Notion-Version header sent with every request
+func WithNotionVersion(version string) Option {
+	return func(c *Client) {
+		c.notionVersion = version
+	}
+}
+
+// RequestHook is called with every request attempt right before it is sent
+type RequestHook func(req *http.Request)
+
+// WithRequestHook adds hook to the hooks called before sending a request, hooks are called in the order they are added
+func WithRequestHook(hook RequestHook) Option {
+	return func(c *Client) {
+		c.hooks = append(c.hooks, hook)
+	}
+}
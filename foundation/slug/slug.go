@@ -0,0 +1,26 @@
+// Package slug creates URL-safe slugs of titles, tag pages and heading anchors share it so their URLs are built alike
+package slug
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Make returns the slug of s made of its lowercase letters and digits, runs of any other characters become a single
+// dash between them, e.g. "Go & Testing!" is go-testing, it's empty if s has no letters or digits
+func Make(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
@@ -0,0 +1,21 @@
+package slug
+
+import "testing"
+
+func TestMake(t *testing.T) {
+	tests := map[string]string{
+		"Go":                  "go",
+		"Go & Testing!":       "go-testing",
+		"  Getting   Started": "getting-started",
+		"C++/Rust":            "c-rust",
+		"Déjà Vu 2":           "déjà-vu-2",
+		"Setup?!":             "setup",
+		"!?":                  "",
+		"":                    "",
+	}
+	for s, want := range tests {
+		if got := Make(s); got != want {
+			t.Errorf("slug of %q should be %q, got: %q", s, want, got)
+		}
+	}
+}
@@ -1,6 +1,6 @@
 /** @type {import('tailwindcss').Config} */
 module.exports = {
-  content: ["./business/templates/**/*.templ"],
+  content: ["./business/templates/**/*.templ", "./business/templates/components/elements/*.go", "./business/templates/pages/blog/blog.go"],
   theme: {
     extend: {
       fontFamily: {